@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/render"
+)
+
+// renderAnimation renders frames from renderTime to -end every -step into the
+// -animate directory as frame_00000.png, frame_00001.png, ... The camera
+// follows the -keyframes path or the orbit, if any, and stays put otherwise.
+// Frames already on disk are skipped, so an interrupted run resumes where it
+// stopped; each frame is written to a temporary file first so a partial frame
+// is never mistaken for a finished one.
+func renderAnimation(ctx context.Context, cfg config, renderTime time.Time, renderer *render.Renderer) error {
+	var path *render.CameraPath
+	if *cfg.keyframes != "" {
+		path = readKeyframesOrExit(*cfg.keyframes)
+		if !isFlagSet("time") {
+			renderTime = path.Start()
+		}
+	}
+
+	var end time.Time
+	switch {
+	case *cfg.end != "":
+		end = parseTimeOrExit(*cfg.end)
+	case path != nil:
+		end = path.End()
+	default:
+		log.Fatalf("-animate needs -end (or -keyframes)")
+	}
+	if *cfg.step <= 0 {
+		log.Fatalf("Invalid -step %v: must be positive", *cfg.step)
+	}
+	if end.Before(renderTime) {
+		log.Fatalf("-end %s is before the start time %s", end.Format(time.RFC3339), renderTime.Format(time.RFC3339))
+	}
+	frames := int(end.Sub(renderTime) / *cfg.step) + 1
+
+	dir := *cfg.animate
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	width, height := outputSize(cfg)
+	crop := cropRect(cfg)
+	for i := 0; i < frames; i++ {
+		framePath := filepath.Join(dir, fmt.Sprintf("frame_%05d.png", i))
+		if _, err := os.Stat(framePath); err == nil {
+			continue
+		}
+
+		t := renderTime.Add(time.Duration(i) * *cfg.step)
+		cell := sheetCell{lat: *cfg.lat, lon: *cfg.lon, alt: *cfg.alt, time: t}
+		var camera render.Camera
+		switch {
+		case path != nil:
+			camera = withLens(cfg, path.Camera(t))
+			cell.lat, cell.lon, cell.alt = earth.LatLonAlt(camera.Position)
+		case cfg.propagator != nil:
+			cell.lat, cell.lon, cell.alt = orbitPosition(cfg.propagator, t)
+			camera = newCamera(cfg, cell.lat, cell.lon, cell.alt, t)
+		default:
+			camera = newCamera(cfg, cell.lat, cell.lon, cell.alt, t)
+		}
+
+		img, err := renderer.Render(ctx, camera, earth.SunDirectionECEF(t), render.Options{
+			Width:         width,
+			Height:        height,
+			Supersampling: *cfg.supersample,
+			Progress:      newProgress(cfg, fmt.Sprintf("frame %d/%d", i+1, frames)),
+			Crop:          crop,
+			Time:          t,
+		})
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		if *cfg.captions {
+			drawCaption(img, img.Bounds(), cell.caption())
+		}
+
+		tmp := framePath + ".tmp"
+		if err := writePNG(tmp, img); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		if err := os.Rename(tmp, framePath); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// readKeyframesOrExit reads a keyframe file of comma-separated
+// time,lat,lon,alt,fov,tilt,yaw,roll lines, with the time in RFC3339 format
+// and '#' starting a comment, and builds the camera path through them.
+func readKeyframesOrExit(path string) *render.CameraPath {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not open keyframe file: %v", err)
+	}
+	defer f.Close()
+
+	keys, err := readKeyframes(f)
+	if err == nil {
+		var p *render.CameraPath
+		if p, err = render.NewCameraPath(keys); err == nil {
+			return p
+		}
+	}
+	log.Fatalf("Could not read keyframe file %s: %v", path, err)
+	return nil
+}
+
+func readKeyframes(r io.Reader) ([]render.Keyframe, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 8
+	cr.TrimLeadingSpace = true
+
+	var keys []render.Keyframe
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, err
+		}
+		var v [7]float64
+		for i := range v {
+			v[i], err = strconv.ParseFloat(strings.TrimSpace(rec[i+1]), 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, render.Keyframe{
+			Time: t,
+			Lat:  v[0], Lon: v[1], Alt: v[2],
+			FOV: v[3], Tilt: v[4], Yaw: v[5], Roll: v[6],
+		})
+	}
+	return keys, nil
+}
@@ -0,0 +1,106 @@
+// Package attitude implements spacecraft pointing modes. Each mode turns an
+// orbit.State into the Forward/Right/Up camera basis used by render.Camera.
+package attitude
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/orbit"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// Basis is an orthonormal camera orientation in ECEF, with the same
+// conventions as render.Camera: Right = Forward × Up-hint, Up = Right × Forward.
+type Basis struct {
+	Forward vectors.Vec3
+	Right   vectors.Vec3
+	Up      vectors.Vec3
+}
+
+// Provider computes the camera attitude for a spacecraft state.
+type Provider interface {
+	Attitude(s orbit.State) (Basis, error)
+}
+
+// Nadir points at Earth's center with Up along the direction of flight, so
+// the ground track runs from the bottom to the top of the frame.
+type Nadir struct{}
+
+// VelocityForward is the LVLH "look ahead" mode: Forward along the
+// horizontal component of the ground-relative velocity, Up toward zenith.
+type VelocityForward struct{}
+
+// InertialFixed holds a fixed direction in inertial space, given as right
+// ascension and declination (deg), with Up toward the celestial north pole.
+// Seen from the rotating Earth the view drifts by one turn per sidereal day.
+type InertialFixed struct {
+	RA, Dec float64
+}
+
+// SunPointing points at the Sun with Up as close to north as possible.
+type SunPointing struct{}
+
+// TargetTracking keeps a ground coordinate (deg, km) on the optical axis with
+// Up as close to north as possible, like render.NewLookAtCamera.
+type TargetTracking struct {
+	Lat, Lon, Alt float64
+}
+
+var north = vectors.Vec3{Z: 1}
+
+func (Nadir) Attitude(s orbit.State) (Basis, error) {
+	fwd := s.Position.Normalize().Scale(-1)
+	if s.Velocity.Norm() == 0 {
+		return fromHint(fwd, north), nil
+	}
+	return fromHint(fwd, s.Velocity), nil
+}
+
+func (VelocityForward) Attitude(s orbit.State) (Basis, error) {
+	zenith := s.Position.Normalize()
+	horizontal := s.Velocity.Sub(zenith.Scale(s.Velocity.Dot(zenith)))
+	if horizontal.Norm() < 1e-9 {
+		return Basis{}, fmt.Errorf("attitude: velocity-forward needs a horizontal velocity")
+	}
+	return fromHint(horizontal.Normalize(), zenith), nil
+}
+
+func (m InertialFixed) Attitude(s orbit.State) (Basis, error) {
+	ra := m.RA * math.Pi / 180.0
+	dec := m.Dec * math.Pi / 180.0
+	dirECI := vectors.Vec3{
+		X: math.Cos(dec) * math.Cos(ra),
+		Y: math.Cos(dec) * math.Sin(ra),
+		Z: math.Sin(dec),
+	}
+	// Earth's rotation axis is shared by ECI and ECEF, so north is unchanged.
+	return fromHint(earth.ECIToECEF(dirECI, s.Time), north), nil
+}
+
+func (SunPointing) Attitude(s orbit.State) (Basis, error) {
+	return fromHint(earth.SunDirectionECEF(s.Time), north), nil
+}
+
+func (m TargetTracking) Attitude(s orbit.State) (Basis, error) {
+	target := earth.PositionECEF(m.Lat, m.Lon, m.Alt)
+	fwd := target.Sub(s.Position).Normalize()
+	if fwd.Norm() == 0 {
+		return Basis{}, fmt.Errorf("attitude: camera is at the tracked target")
+	}
+	return fromHint(fwd, north), nil
+}
+
+// fromHint builds a basis looking along fwd with Up as close to upHint as
+// possible, falling back to any perpendicular when they are parallel.
+func fromHint(fwd, upHint vectors.Vec3) Basis {
+	fwd = fwd.Normalize()
+	right := fwd.Cross(upHint)
+	if right.Norm() < 1e-6*upHint.Norm() {
+		right = fwd.Orthogonal()
+	}
+	right = right.Normalize()
+	up := right.Cross(fwd).Normalize()
+	return Basis{Forward: fwd, Right: right, Up: up}
+}
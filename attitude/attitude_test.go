@@ -0,0 +1,58 @@
+package attitude
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/orbit"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+func TestModes(t *testing.T) {
+	state := orbit.State{
+		Time:     time.Date(2025, 6, 21, 12, 0, 0, 0, time.UTC),
+		Position: earth.PositionECEF(30, 40, 700),
+		Velocity: vectors.Vec3{X: -3, Y: 2, Z: 6},
+	}
+
+	modes := map[string]Provider{
+		"nadir":    Nadir{},
+		"lvlh":     VelocityForward{},
+		"inertial": InertialFixed{RA: 80, Dec: 20},
+		"sun":      SunPointing{},
+		"target":   TargetTracking{Lat: 32, Lon: 41},
+	}
+	for name, mode := range modes {
+		b, err := mode.Attitude(state)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, v := range []vectors.Vec3{b.Forward, b.Right, b.Up} {
+			if math.Abs(v.Norm()-1) > 1e-9 {
+				t.Errorf("%s: basis vector %+v is not unit length", name, v)
+			}
+		}
+		if math.Abs(b.Forward.Dot(b.Right)) > 1e-9 || math.Abs(b.Forward.Dot(b.Up)) > 1e-9 || math.Abs(b.Right.Dot(b.Up)) > 1e-9 {
+			t.Errorf("%s: basis is not orthogonal: %+v", name, b)
+		}
+		if vectors.Distance(b.Right, b.Forward.Cross(b.Up)) > 1e-9 {
+			t.Errorf("%s: basis handedness differs from render.Camera: %+v", name, b)
+		}
+	}
+
+	b, _ := Nadir{}.Attitude(state)
+	if b.Up.Dot(state.Velocity) <= 0 {
+		t.Errorf("nadir: Up %+v does not point along the velocity", b.Up)
+	}
+	b, _ = VelocityForward{}.Attitude(state)
+	if b.Up.Dot(state.Position.Normalize()) < 0.999999 {
+		t.Errorf("lvlh: Up %+v is not zenith", b.Up)
+	}
+	b, _ = TargetTracking{Lat: 32, Lon: 41}.Attitude(state)
+	toTarget := earth.PositionECEF(32, 41, 0).Sub(state.Position).Normalize()
+	if vectors.Distance(b.Forward, toTarget) > 1e-9 {
+		t.Errorf("target: Forward %+v, want %+v", b.Forward, toTarget)
+	}
+}
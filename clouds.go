@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/render"
+)
+
+// readCloudFramesOrExit reads a cloud frame file of comma-separated time,path
+// lines, with the time in RFC3339 format and '#' starting a comment. Relative
+// paths are relative to the directory of the file.
+func readCloudFramesOrExit(path string) []render.TimedPath {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not open cloud frame file: %v", err)
+	}
+	defer f.Close()
+
+	frames, err := readCloudFrames(f, filepath.Dir(path))
+	if err == nil && len(frames) == 0 {
+		err = errors.New("no frames")
+	}
+	if err != nil {
+		log.Fatalf("Could not read cloud frame file %s: %v", path, err)
+	}
+	return frames
+}
+
+func readCloudFrames(r io.Reader, dir string) ([]render.TimedPath, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	var frames []render.TimedPath
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, err
+		}
+		p := strings.TrimSpace(rec[1])
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		frames = append(frames, render.TimedPath{Time: t, Path: p})
+	}
+	return frames, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/render"
+)
+
+func TestReadCloudFrames(t *testing.T) {
+	dir := filepath.FromSlash("/data/clouds")
+	abs, err := filepath.Abs(filepath.FromSlash("/elsewhere/c.tif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    []render.TimedPath
+		wantErr bool
+	}{
+		{
+			name: "relative and absolute paths",
+			in:   "2024-08-08T00:00:00Z, a.tif\n2024-08-08T03:00:00Z, sub/b.tif\n2024-08-08T06:00:00Z," + abs + "\n",
+			want: []render.TimedPath{
+				{Time: t0, Path: filepath.Join(dir, "a.tif")},
+				{Time: t0.Add(3 * time.Hour), Path: filepath.Join(dir, "sub", "b.tif")},
+				{Time: t0.Add(6 * time.Hour), Path: abs},
+			},
+		},
+		{
+			name: "comments",
+			in:   "# time, path\n2024-08-08T00:00:00Z, a.tif\n# the end\n",
+			want: []render.TimedPath{{Time: t0, Path: filepath.Join(dir, "a.tif")}},
+		},
+		{name: "bad time", in: "2024-08-08 00:00, a.tif\n", wantErr: true},
+		{name: "too few fields", in: "2024-08-08T00:00:00Z\n", wantErr: true},
+		{name: "too many fields", in: "2024-08-08T00:00:00Z, a.tif, b.tif\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := readCloudFrames(strings.NewReader(tt.in), dir)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Time.Equal(tt.want[i].Time) || got[i].Path != tt.want[i].Path {
+				t.Errorf("%s: frame %d = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
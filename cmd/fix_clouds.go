@@ -0,0 +1,307 @@
+//go:build ignore
+
+// fix_clouds repairs a global cloud texture like NASA's Blue Marble clouds,
+// which leaves the poles black and has a visible seam where its left edge
+// meets the right:
+//
+//	go run cmd/fix_clouds.go [-feather 128] [-max-cap 30] [-empty 0.95] <input> <output>
+//
+// The seam is feathered away over -feather pixels on each side, and the
+// black polar caps, the rows near each pole that are almost all black, are
+// filled in from the nearest rows with data. The output may be PNG, JPEG or
+// a tiled TIFF (.tif) for use with -clouds.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/echoflaresat/spacecam/render"
+	"github.com/echoflaresat/spacecam/tiffenc"
+)
+
+const (
+	// emptyLevel is the channel value at or below which a pixel counts as
+	// black; JPEG ringing along the edge of the data reaches a few levels.
+	emptyLevel = 10
+
+	// inpaintSpread is the width of the sideways blur of each filled row,
+	// in rows of ground distance. Wider hides the streaks of extending the
+	// data toward the pole; narrower keeps more of its structure.
+	inpaintSpread = 3
+
+	// seamEdge is how many columns on each side of the seam are averaged
+	// into the color of each edge.
+	seamEdge = 4
+
+	// seamRatio is how much more the colors must jump across the seam than
+	// between neighbouring columns elsewhere for the seam to be feathered.
+	seamRatio = 1.5
+)
+
+func main() {
+	feather := flag.Int("feather", 128, "Width in pixels of the band on each side of the seam over which it is blended away; 0 leaves the seam")
+	maxCap := flag.Float64("max-cap", 30, "Largest polar cap in degrees from the pole to fill in; 0 leaves the poles")
+	empty := flag.Float64("empty", 0.95, "Fraction of black pixels above which a row near a pole belongs to the missing cap")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <input> <output.png|.jpg|.tif>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	img := load(flag.Arg(0))
+	if *feather > 0 {
+		featherSeam(img, *feather)
+	}
+	if *maxCap > 0 {
+		fillPoles(img, *maxCap, *empty)
+	}
+	save(flag.Arg(1), img)
+}
+
+func load(path string) *image.NRGBA {
+	fmt.Printf("Processing %s\n", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not open %q: %v", path, err)
+	}
+	defer f.Close()
+
+	src, err := render.LoadImage(f)
+	if err != nil {
+		log.Fatalf("Could not load %q: %v", path, err)
+	}
+	b := src.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	return img
+}
+
+// featherSeam measures the jump in color between the first and last column
+// and, if it stands out from the texture's own detail, spreads the
+// difference of each row over band pixels on either side, so that both
+// edges meet at their average.
+func featherSeam(img *image.NRGBA, band int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	band = min(band, w/2)
+
+	seam, interior := seamContrast(img)
+	fmt.Printf("Seam contrast %.1f, elsewhere %.1f\n", seam, interior)
+	if seam <= seamRatio*interior {
+		fmt.Println("-> no seam to feather")
+		return
+	}
+
+	diff := make([]float64, h*3)
+	for y := 0; y < h; y++ {
+		for i := 0; i < seamEdge; i++ {
+			left, right := img.PixOffset(i, y), img.PixOffset(w-1-i, y)
+			for c := 0; c < 3; c++ {
+				diff[y*3+c] += (float64(img.Pix[left+c]) - float64(img.Pix[right+c])) / seamEdge
+			}
+		}
+	}
+
+	// The difference is averaged over as many rows as the band is wide, so
+	// that the correction varies as smoothly down the seam as across it
+	// instead of smearing the noise of single rows sideways into streaks.
+	fmt.Printf("-> feathering the seam over %d pixels\n", band)
+	for y := 0; y < h; y++ {
+		var d [3]float64
+		n := 0
+		for j := max(y-band/2, 0); j <= min(y+band/2, h-1); j++ {
+			for c := range d {
+				d[c] += diff[j*3+c]
+			}
+			n++
+		}
+		for i := 0; i < band; i++ {
+			// Half the difference at the edge, fading to none at the
+			// inner end of the band.
+			wgt := 0.5 * (1 - float64(i)/float64(band)) / float64(n)
+			left, right := img.PixOffset(i, y), img.PixOffset(w-1-i, y)
+			for c := range d {
+				img.Pix[left+c] = clampByte(float64(img.Pix[left+c]) - wgt*d[c])
+				img.Pix[right+c] = clampByte(float64(img.Pix[right+c]) + wgt*d[c])
+			}
+		}
+	}
+}
+
+// seamContrast returns the mean absolute difference between the first and
+// last column, and between neighbouring columns at a quarter, half and three
+// quarters of the width for comparison.
+func seamContrast(img *image.NRGBA) (seam, interior float64) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	colDiff := func(x0, x1 int) float64 {
+		var sum float64
+		for y := 0; y < h; y++ {
+			a, b := img.PixOffset(x0, y), img.PixOffset(x1, y)
+			for c := 0; c < 3; c++ {
+				sum += math.Abs(float64(img.Pix[a+c]) - float64(img.Pix[b+c]))
+			}
+		}
+		return sum / float64(h*3)
+	}
+
+	seam = colDiff(0, w-1)
+	for _, x := range []int{w / 4, w / 2, 3 * w / 4} {
+		interior += colDiff(x, x+1) / 3
+	}
+	return seam, interior
+}
+
+// fillPoles finds the missing polar caps, the rows from each pole, but no
+// more than maxCap degrees from it, of which more than the fraction empty of
+// pixels are black, and fills their black pixels in row by row toward the
+// pole from the rows on the equator side. The extent is measured by rows
+// rather than columns because black is also clear sky, which a cap would
+// otherwise run on into wherever the two touch.
+func fillPoles(img *image.NRGBA, maxCap, empty float64) {
+	h := img.Rect.Dy()
+	limit := min(int(maxCap/180*float64(h)), h/2)
+
+	north := 0
+	for north < limit && emptyShare(img, north) > empty {
+		north++
+	}
+	south := 0
+	for south < limit && emptyShare(img, h-1-south) > empty {
+		south++
+	}
+	fmt.Printf("-> filling the north cap above %.1f°N (%d rows) and the south cap below %.1f°S (%d rows)\n",
+		90-float64(north)*180/float64(h), north, 90-float64(south)*180/float64(h), south)
+
+	for y := north - 1; y >= 0; y-- {
+		inpaintRow(img, y, y+1, func(x int) bool { return isEmpty(img, x, y) })
+	}
+	for y := h - south; y < h; y++ {
+		inpaintRow(img, y, y-1, func(x int) bool { return isEmpty(img, x, y) })
+	}
+}
+
+// emptyShare returns the fraction of the pixels of row y that are black.
+func emptyShare(img *image.NRGBA, y int) float64 {
+	w := img.Rect.Dx()
+	black := 0
+	for x := 0; x < w; x++ {
+		if isEmpty(img, x, y) {
+			black++
+		}
+	}
+	return float64(black) / float64(w)
+}
+
+// isEmpty reports whether the pixel at (x, y) is black.
+func isEmpty(img *image.NRGBA, x, y int) bool {
+	i := img.PixOffset(x, y)
+	return img.Pix[i] <= emptyLevel && img.Pix[i+1] <= emptyLevel && img.Pix[i+2] <= emptyLevel
+}
+
+// inpaintRow sets the missing pixels of row y to row from, blurred sideways
+// over inpaintSpread times the ground distance one row spans north to south.
+// Toward the pole a pixel spans less and less ground, so the blur widens
+// until, at the pole itself, the row is a single averaged color, as the
+// single point there must be.
+func inpaintRow(img *image.NRGBA, y, from int, missing func(x int) bool) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	lat := (0.5 - (float64(y)+0.5)/float64(h)) * math.Pi
+	rowDeg, pixelDeg := 180/float64(h), 360/float64(w)
+	r := int(math.Ceil(inpaintSpread * rowDeg / (pixelDeg * math.Cos(lat))))
+
+	// Running sums over the wrapped row, so that the blur crosses the
+	// antimeridian like the texture does.
+	var sum [3]int
+	n := min(2*r+1, w)
+	if n == w {
+		r = 0
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(x, from)
+			for c := range sum {
+				sum[c] += int(img.Pix[i+c])
+			}
+		}
+	} else {
+		for x := -r; x <= r; x++ {
+			i := img.PixOffset((x+w)%w, from)
+			for c := range sum {
+				sum[c] += int(img.Pix[i+c])
+			}
+		}
+	}
+
+	for x := 0; x < w; x++ {
+		if missing(x) {
+			o := img.PixOffset(x, y)
+			for c := range sum {
+				img.Pix[o+c] = clampByte(float64(sum[c]) / float64(n))
+			}
+			img.Pix[o+3] = 0xff
+		}
+		if n == w {
+			continue
+		}
+		in, out := img.PixOffset((x+r+1)%w, from), img.PixOffset((x-r+w)%w, from)
+		for c := range sum {
+			sum[c] += int(img.Pix[in+c]) - int(img.Pix[out+c])
+		}
+	}
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(min(max(math.Round(v), 0), 255))
+}
+
+func save(output string, img *image.NRGBA) {
+	fmt.Printf("-> creating %s\n", output)
+	outFile, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("Could not create %s: %v", output, err)
+	}
+	defer outFile.Close()
+
+	ext := strings.ToLower(filepath.Ext(output))
+	switch ext {
+	case ".png":
+		err = png.Encode(outFile, img)
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(outFile, img, &jpeg.Options{Quality: 95})
+	case ".tif", ".tiff":
+		err = saveTIFF(outFile, img)
+	default:
+		log.Fatalf("Unsupported output format: %s", ext)
+	}
+	if err != nil {
+		log.Fatalf("Failed to encode %s: %v", output, err)
+	}
+}
+
+// saveTIFF writes img as a tiled TIFF that the renderer reads lazily.
+func saveTIFF(f *os.File, img *image.NRGBA) error {
+	tw, err := tiffenc.NewTiled(f, img.Rect.Dx(), img.Rect.Dy(), tiffenc.DefaultTileSize)
+	if err != nil {
+		return err
+	}
+	across, down := tw.Tiles()
+	for row := 0; row < down; row++ {
+		for col := 0; col < across; col++ {
+			if err := tw.WriteTile(col, row, img); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
@@ -13,11 +13,13 @@ import (
 	"strings"
 
 	"github.com/echoflaresat/spacecam/render"
+	"github.com/echoflaresat/spacecam/tiffenc"
 )
 
 func main() {
 	if len(os.Args) < 5 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <cols>x<rows> <output.png> <tile1> <tile2> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s <cols>x<rows> <output.png|.jpg|.tif> <tile1> <tile2> ...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "A .tif output is a tiled classic TIFF, limited to 4 GiB compressed.\n")
 		os.Exit(1)
 	}
 
@@ -41,6 +43,11 @@ func main() {
 		log.Fatalf("Expected %d input files, got %d", cols*rows, len(inputFiles))
 	}
 
+	if ext := strings.ToLower(filepath.Ext(output)); ext == ".tif" || ext == ".tiff" {
+		mergeTIFF(output, cols, rows, inputFiles)
+		return
+	}
+
 	var canvas *image.NRGBA
 	var tileW, tileH int
 	// Draw each tile into its position
@@ -100,3 +107,100 @@ func save(output string, canvas *image.NRGBA) {
 	}
 
 }
+
+// mergeTIFF writes the tiles as one tiled, DEFLATE-compressed TIFF, the
+// layout the renderer reads lazily. It assembles one row of TIFF tiles at a
+// time, with only the input tiles overlapping that row open, so the merged
+// image is never held in memory whole. Being classic TIFF, the output must
+// compress to under 4 GiB; the write fails with tiffenc.ErrTooLarge when it
+// doesn't.
+func mergeTIFF(output string, cols, rows int, inputFiles []string) {
+	open := make(map[int]inputTile)
+	first := loadTile(inputFiles[0])
+	open[0] = first
+	tileW, tileH := first.img.Bounds().Dx(), first.img.Bounds().Dy()
+	width, height := cols*tileW, rows*tileH
+
+	tile := func(idx int) inputTile {
+		if t, ok := open[idx]; ok {
+			return t
+		}
+		t := loadTile(inputFiles[idx])
+		if b := t.img.Bounds(); b.Dx() != tileW || b.Dy() != tileH {
+			log.Fatalf("Tile size mismatch for %q: expected %dx%d, got %dx%d",
+				inputFiles[idx], tileW, tileH, b.Dx(), b.Dy())
+		}
+		open[idx] = t
+		return t
+	}
+
+	fmt.Printf("-> creating %s (%dx%d)\n", output, width, height)
+	outFile, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("Could not create %s: %v", output, err)
+	}
+	defer outFile.Close()
+
+	tw, err := tiffenc.NewTiled(outFile, width, height, tiffenc.DefaultTileSize)
+	if err != nil {
+		log.Fatalf("Could not create %s: %v", output, err)
+	}
+	across, down := tw.Tiles()
+	buf := make([]uint8, width*tiffenc.DefaultTileSize*4)
+	for row := 0; row < down; row++ {
+		r := tw.TileBounds(0, row)
+		r.Max.X = width
+		band := &image.NRGBA{Pix: buf[:r.Dy()*width*4], Stride: width * 4, Rect: r}
+
+		// Draw the part of each input tile that falls into the band.
+		for inRow := r.Min.Y / tileH; inRow <= (r.Max.Y-1)/tileH; inRow++ {
+			for col := 0; col < cols; col++ {
+				t := tile(inRow*cols + col)
+				at := image.Pt(col*tileW, inRow*tileH)
+				dst := image.Rect(at.X, at.Y, at.X+tileW, at.Y+tileH).Intersect(r)
+				draw.Draw(band, dst, t.img, t.img.Bounds().Min.Add(dst.Min.Sub(at)), draw.Src)
+			}
+		}
+
+		for col := 0; col < across; col++ {
+			if err := tw.WriteTile(col, row, band); err != nil {
+				log.Fatalf("Could not write %s: %v", output, err)
+			}
+		}
+
+		// Close the input tiles the rest of the image does not reach.
+		for idx, t := range open {
+			if (idx/cols+1)*tileH <= r.Max.Y {
+				t.file.Close()
+				delete(open, idx)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		log.Fatalf("Could not write %s: %v", output, err)
+	}
+	if err := outFile.Close(); err != nil {
+		log.Fatalf("Could not write %s: %v", output, err)
+	}
+}
+
+// inputTile is an input image and the file it is read from, which stays
+// open while the image is in use because TIFFs are decoded lazily.
+type inputTile struct {
+	img  image.Image
+	file *os.File
+}
+
+func loadTile(path string) inputTile {
+	fmt.Printf("Processing %s\n", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not load input file %q: %v", path, err)
+	}
+	img, err := render.LoadImage(f)
+	if err != nil {
+		log.Fatalf("Could not load input file %q: %v", path, err)
+	}
+	return inputTile{img, f}
+}
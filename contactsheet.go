@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/render"
+)
+
+// sheetCell is the camera position and time of one contact sheet cell.
+type sheetCell struct {
+	lat, lon, alt float64
+	time          time.Time
+}
+
+// renderContactSheet renders a grid of views into one image. Cells are
+// numbered in reading order, and cell k is offset from the base camera by k
+// times each of -step-lat, -step-lon, -step-alt and -step-time. With -tle the
+// base camera follows the orbit to each cell's time.
+func renderContactSheet(ctx context.Context, cfg config, renderTime time.Time, renderer *render.Renderer) (image.Image, error) {
+	cols, rows, err := parseGrid(*cfg.grid)
+	if err != nil {
+		log.Fatalf("Invalid -grid: %v", err)
+	}
+
+	canvasW, canvasH := outputSize(cfg)
+	if canvasW%cols != 0 || canvasH%rows != 0 {
+		log.Fatalf("size must be divisible by the %dx%d grid; got %dx%d", cols, rows, canvasW, canvasH)
+	}
+	tileW, tileH := canvasW/cols, canvasH/rows
+
+	out := image.NewRGBA(image.Rect(0, 0, canvasW, canvasH))
+	for k := 0; k < cols*rows; k++ {
+		cellTime := renderTime.Add(time.Duration(k) * *cfg.stepTime)
+		lat, lon, alt := *cfg.lat, *cfg.lon, *cfg.alt
+		if cfg.propagator != nil {
+			lat, lon, alt = orbitPosition(cfg.propagator, cellTime)
+		}
+		cell := sheetCell{
+			lat:  lat + float64(k)**cfg.stepLat,
+			lon:  lon + float64(k)**cfg.stepLon,
+			alt:  alt + float64(k)**cfg.stepAlt,
+			time: cellTime,
+		}
+
+		camera := newCamera(cfg, cell.lat, cell.lon, cell.alt, cell.time)
+		img, err := renderer.Render(ctx, camera, earth.SunDirectionECEF(cell.time), render.Options{
+			Width:         tileW,
+			Height:        tileH,
+			Supersampling: *cfg.supersample,
+			Progress:      newProgress(cfg, fmt.Sprintf("%s %d/%d", *cfg.out, k+1, cols*rows)),
+			Time:          cell.time,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		min := image.Point{X: (k % cols) * tileW, Y: (k / cols) * tileH}
+		dst := image.Rectangle{Min: min, Max: min.Add(image.Point{tileW, tileH})}
+		draw.Draw(out, dst, img, image.Point{}, draw.Src)
+
+		if *cfg.captions {
+			drawCaption(out, dst, cell.caption())
+		}
+	}
+	return out, nil
+}
+
+// parseGrid parses a "<cols>x<rows>" layout.
+func parseGrid(s string) (int, int, error) {
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%q is not of the form <cols>x<rows>", s)
+	}
+	cols, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cols: %w", err)
+	}
+	rows, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid rows: %w", err)
+	}
+	if cols < 1 || rows < 1 {
+		return 0, 0, fmt.Errorf("grid must be at least 1x1, got %dx%d", cols, rows)
+	}
+	return cols, rows, nil
+}
+
+func (c sheetCell) caption() []string {
+	// Wrap longitude into [-180, 180) for display.
+	lon := math.Mod(c.lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	lon -= 180
+
+	return []string{
+		fmt.Sprintf("LAT %.1f LON %.1f ALT %.0f KM", c.lat, lon, c.alt),
+		c.time.UTC().Format("2006-01-02 15:04Z"),
+	}
+}
+
+// drawCaption writes lines of text on a translucent band at the bottom of
+// rect, scaling the glyphs with the tile width.
+func drawCaption(img draw.Image, rect image.Rectangle, lines []string) {
+	scale := max(1, rect.Dx()/400)
+	lineH := (glyphH + 3) * scale
+	pad := 2 * scale
+
+	band := image.Rect(rect.Min.X, rect.Max.Y-len(lines)*lineH-2*pad, rect.Max.X, rect.Max.Y).Intersect(rect)
+	draw.Draw(img, band, image.NewUniform(color.NRGBA{0, 0, 0, 160}), image.Point{}, draw.Over)
+
+	y := band.Min.Y + pad
+	for _, line := range lines {
+		drawText(img, rect, image.Point{X: rect.Min.X + pad, Y: y}, line, scale, color.White)
+		y += lineH
+	}
+}
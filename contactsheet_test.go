@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseGrid(t *testing.T) {
+	tests := []struct {
+		in         string
+		cols, rows int
+		wantErr    bool
+	}{
+		{in: "2x2", cols: 2, rows: 2},
+		{in: "3x1", cols: 3, rows: 1},
+		{in: "0x2", wantErr: true},
+		{in: "x3", wantErr: true},
+		{in: "2x2x2", wantErr: true},
+	}
+	for _, tt := range tests {
+		cols, rows, err := parseGrid(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGrid(%q) = %d, %d, want an error", tt.in, cols, rows)
+			}
+			continue
+		}
+		if err != nil || cols != tt.cols || rows != tt.rows {
+			t.Errorf("parseGrid(%q) = %d, %d, %v, want %d, %d", tt.in, cols, rows, err, tt.cols, tt.rows)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package earth
 
 import (
+	"math"
 	"time"
 
 	"github.com/echoflaresat/spacecam/vectors"
@@ -13,6 +14,31 @@ const Radius = 6371.0 // Earth radius in km (spherical approximation)
 const AtmosphereKm = 200
 const RadiusWithAtmosphere = Radius + AtmosphereKm
 
+// RotationRate is Earth's sidereal rotation rate in rad/s.
+const RotationRate = 7.2921158553e-5
+
+// PositionECEF converts geodetic lat/lon (deg) and altitude above the
+// spherical surface (km) into an ECEF position in km.
+func PositionECEF(latDeg, lonDeg, altKm float64) vectors.Vec3 {
+	lat := latDeg * math.Pi / 180.0
+	lon := lonDeg * math.Pi / 180.0
+
+	r := Radius + altKm
+	x := r * math.Cos(lat) * math.Cos(lon)
+	y := r * math.Cos(lat) * math.Sin(lon)
+	z := r * math.Sin(lat)
+
+	return vectors.Vec3{X: x, Y: y, Z: z}
+}
+
+// LatLonAlt is the inverse of PositionECEF: it returns the lat/lon (deg) and
+// altitude above the spherical surface (km) of an ECEF position.
+func LatLonAlt(p vectors.Vec3) (latDeg, lonDeg, altKm float64) {
+	lat := math.Atan2(p.Z, math.Hypot(p.X, p.Y))
+	lon := math.Atan2(p.Y, p.X)
+	return lat * 180.0 / math.Pi, lon * 180.0 / math.Pi, p.Norm() - Radius
+}
+
 func SunDirectionECEF(t time.Time) vectors.Vec3 {
 	t = t.UTC()
 	jd := julian.TimeToJD(t)
@@ -26,13 +52,77 @@ func SunDirectionECEF(t time.Time) vectors.Vec3 {
 	z := dec.Sin()
 
 	// Step 3: Rotate ECI → ECEF using GMST
+	return ECIToECEF(vectors.Vec3{X: x, Y: y, Z: z}, t)
+}
+
+// ECIToECEF rotates an ECI vector (equator and equinox of date) into ECEF at
+// time t using Greenwich apparent sidereal time. Polar motion is ignored.
+func ECIToECEF(v vectors.Vec3, t time.Time) vectors.Vec3 {
+	cosGAST, sinGAST := siderealRotation(t)
+	return rotateToECEF(v, cosGAST, sinGAST)
+}
+
+// TEMEToECEF rotates a vector in the True Equator Mean Equinox frame that
+// SGP4 works in into ECEF at time t. TEME is tied to the mean equinox, so it
+// turns by Greenwich mean sidereal time (Vallado et al. 2006) rather than
+// the apparent time ECIToECEF uses; the two differ by the equation of the
+// equinoxes, about half a kilometer in low orbit.
+func TEMEToECEF(v vectors.Vec3, t time.Time) vectors.Vec3 {
+	cosGMST, sinGMST := meanSiderealRotation(t)
+	return rotateToECEF(v, cosGMST, sinGMST)
+}
+
+// ECEFToECI is the inverse of ECIToECEF.
+func ECEFToECI(v vectors.Vec3, t time.Time) vectors.Vec3 {
+	cosGMST, sinGMST := siderealRotation(t)
+
+	x := v.X*cosGMST - v.Y*sinGMST
+	y := v.X*sinGMST + v.Y*cosGMST
+
+	return vectors.Vec3{X: x, Y: y, Z: v.Z}
+}
+
+// ECIToECEFState converts an ECI position (km) and velocity (km/s) into the
+// rotating ECEF frame at time t.
+func ECIToECEFState(pos, vel vectors.Vec3, t time.Time) (vectors.Vec3, vectors.Vec3) {
+	posECEF := ECIToECEF(pos, t)
+	omega := vectors.Vec3{Z: RotationRate}
+	velECEF := ECIToECEF(vel, t).Sub(omega.Cross(posECEF))
+	return posECEF, velECEF
+}
+
+// TEMEToECEFState converts a TEME position (km) and velocity (km/s) into the
+// rotating ECEF frame at time t.
+func TEMEToECEFState(pos, vel vectors.Vec3, t time.Time) (vectors.Vec3, vectors.Vec3) {
+	posECEF := TEMEToECEF(pos, t)
+	omega := vectors.Vec3{Z: RotationRate}
+	velECEF := TEMEToECEF(vel, t).Sub(omega.Cross(posECEF))
+	return posECEF, velECEF
+}
+
+// ECEFToECIState is the inverse of ECIToECEFState.
+func ECEFToECIState(pos, vel vectors.Vec3, t time.Time) (vectors.Vec3, vectors.Vec3) {
+	omega := vectors.Vec3{Z: RotationRate}
+	velInertial := vel.Add(omega.Cross(pos))
+	return ECEFToECI(pos, t), ECEFToECI(velInertial, t)
+}
+
+func siderealRotation(t time.Time) (float64, float64) {
+	jd := julian.TimeToJD(t.UTC())
 	gmst := sidereal.Apparent0UT(jd)
-	cosGMST := gmst.Angle().Cos()
-	sinGMST := gmst.Angle().Sin()
+	return gmst.Angle().Cos(), gmst.Angle().Sin()
+}
 
-	xe := x*cosGMST + y*sinGMST
-	ye := -x*sinGMST + y*cosGMST
-	ze := z
+func meanSiderealRotation(t time.Time) (float64, float64) {
+	jd := julian.TimeToJD(t.UTC())
+	gmst := sidereal.Mean(jd)
+	return gmst.Angle().Cos(), gmst.Angle().Sin()
+}
 
-	return vectors.Vec3{X: xe, Y: ye, Z: ze}
+// rotateToECEF turns v about the Z axis by the sidereal angle whose cosine
+// and sine are given.
+func rotateToECEF(v vectors.Vec3, cosTheta, sinTheta float64) vectors.Vec3 {
+	x := v.X*cosTheta + v.Y*sinTheta
+	y := -v.X*sinTheta + v.Y*cosTheta
+	return vectors.Vec3{X: x, Y: y, Z: v.Z}
 }
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"image"
-	"image/draw"
 	"image/png"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/echoflaresat/spacecam/attitude"
 	"github.com/echoflaresat/spacecam/colors"
 	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/orbit"
 	"github.com/echoflaresat/spacecam/render"
 	"github.com/echoflaresat/spacecam/vectors"
 )
@@ -20,12 +27,49 @@ import (
 type config struct {
 	lat, lon, alt      *float64
 	fov, tilt, yaw     *float64
+	roll               *float64
+	targetLat          *float64
+	targetLon          *float64
+	targetAlt          *float64
 	size, supersample  *int
+	width, height      *int
+	fovAxis            *string
+	projection         *string
 	out                *string
 	day, night, clouds *string
+	filter             *string
+	mipmaps            *bool
+	textureCacheMB     *int
+	cloudFrames        *string
+	cloudMotion        *bool
 	timeStr            *string
 	showHelp           *bool
 	panoramic          *bool
+	grid               *string
+	stepLat, stepLon   *float64
+	stepAlt            *float64
+	stepTime           *time.Duration
+	captions           *bool
+	cubemap            *string
+	tle, tleName       *string
+	elements, eci      *string
+	epoch              *string
+	j2                 *bool
+	attitude           *string
+	ra, dec            *float64
+	animate            *string
+	end                *string
+	step               *time.Duration
+	keyframes          *string
+	timeout            *time.Duration
+	quiet              *bool
+	crop               *string
+	renderTiles        *string
+	tileSize           *int
+	assemble           *string
+
+	// propagator, when set from -tle, positions the camera at the render time.
+	propagator orbit.Propagator
 }
 
 func defineFlags() config {
@@ -34,20 +78,66 @@ func defineFlags() config {
 		lon:  flag.Float64("lon", 120.0, "Camera longitude in degrees"),
 		alt:  flag.Float64("alt", 8880.0, "Camera altitude in kilometers"),
 		fov:  flag.Float64("fov", 60.0, "Camera field of view in degrees"),
-		yaw:  flag.Float64("yaw", 0.0, "Camera yaw in degrees"),
-		tilt: flag.Float64("tilt", 0.0, "Camera tilt in degrees"),
+		yaw:  flag.Float64("yaw", 0.0, "Camera yaw in degrees (applied first, positive pans left)"),
+		tilt: flag.Float64("tilt", 0.0, "Camera tilt in degrees (applied after yaw, positive pitches up)"),
+		roll: flag.Float64("roll", 0.0, "Camera roll in degrees (applied last, about the view direction)"),
+
+		tle:     flag.String("tle", "", "Two-line element file; positions the camera on the propagated orbit at -time (replaces lat/lon/alt)"),
+		tleName: flag.String("tle-name", "", "Satellite name or catalog number to pick from the -tle file (default: first entry)"),
+
+		elements: flag.String("elements", "", "Keplerian elements a(km),e,i,raan,argp,nu(deg) at -epoch; positions the camera like -tle"),
+		eci:      flag.String("eci", "", "ECI state vector x,y,z(km),vx,vy,vz(km/s) at -epoch; positions the camera like -tle"),
+		epoch:    flag.String("epoch", "", "Epoch of -elements/-eci in RFC3339 format; defaults to -time"),
+		j2:       flag.Bool("j2", false, "Include J2 secular drift when propagating -elements/-eci"),
+
+		fovAxis:    flag.String("fov-axis", "horizontal", "Image axis the field of view spans: horizontal, vertical or diagonal"),
+		projection: flag.String("projection", "pinhole", "Lens projection: pinhole, fisheye, equisolid, orthographic or equirectangular"),
+
+		targetLat: flag.Float64("target-lat", 0.0, "Latitude in degrees of a ground point to look at (replaces tilt/yaw)"),
+		targetLon: flag.Float64("target-lon", 0.0, "Longitude in degrees of a ground point to look at (replaces tilt/yaw)"),
+		targetAlt: flag.Float64("target-alt", 0.0, "Altitude in kilometers of the look-at target"),
+
+		attitude: flag.String("attitude", "", "Pointing mode: nadir, lvlh, inertial, sun or target; tilt/yaw/roll are applied on top"),
+		ra:       flag.Float64("ra", 0.0, "Right ascension in degrees for -attitude inertial"),
+		dec:      flag.Float64("dec", 0.0, "Declination in degrees for -attitude inertial"),
 
 		size:        flag.Int("size", 1024, "Output image size (width/height in pixels)"),
+		width:       flag.Int("width", 0, "Output image width in pixels; overrides -size"),
+		height:      flag.Int("height", 0, "Output image height in pixels; overrides -size"),
 		supersample: flag.Int("supersample", 1, "Supersampling factor (higher is slower but smoother)"),
+		crop:        flag.String("crop", "", "Render only the pixel rectangle x,y,w,h of the full -width x -height frame"),
+		timeout:     flag.Duration("timeout", 0, "Give up rendering after this long (e.g. 5m); 0 means no limit"),
 		timeStr:     flag.String("time", "", "Time in RFC3339 format (e.g., 2025-08-02T15:04:05Z); defaults to now"),
 
-		out: flag.String("out", "earth_view.png", "Output PNG file path"),
+		out:   flag.String("out", "earth_view.png", "Output PNG file path; - writes to stdout"),
+		quiet: flag.Bool("quiet", false, "Don't show render progress on stderr"),
+
+		day:            flag.String("day", "assets/world.200408.jpg", "Day texture path; a pattern like world.2004%02d.tif loads twelve monthly textures, blended by -time"),
+		night:          flag.String("night", "assets/night.jpg", "Night texture path"),
+		clouds:         flag.String("clouds", "assets/cloud.2001210.jpg", "Clouds texture path"),
+		filter:         flag.String("filter", "nearest", "Texture filtering: nearest, bilinear or bicubic (smoother coastlines at low altitudes)"),
+		mipmaps:        flag.Bool("mipmaps", false, "Sample textures from image pyramids matched to the pixel footprint (less aliasing at high altitudes); built on first use and cached next to the textures"),
+		textureCacheMB: flag.Int("texture-cache-mb", 256, "Memory budget in MB for decoded tiles of tiled TIFF textures, shared by all textures"),
+		cloudFrames:    flag.String("cloud-frames", "", "File of time,path lines naming time-stamped cloud textures, blended by -time; replaces -clouds"),
+		cloudMotion:    flag.Bool("cloud-motion", false, "Blend -cloud-frames along the cloud motion between them instead of cross-fading in place"),
 
-		day:    flag.String("day", "assets/world.200408.jpg", "Day texture path"),
-		night:  flag.String("night", "assets/night.jpg", "Night texture path"),
-		clouds: flag.String("clouds", "assets/cloud.2001210.jpg", "Clouds texture path"),
+		panoramic: flag.Bool("panoramic", true, "Render a contact sheet: a -grid of views varied by the -step-* flags"),
+		grid:      flag.String("grid", "2x2", "Contact sheet layout as <cols>x<rows>"),
+		stepLat:   flag.Float64("step-lat", 0.0, "Latitude change in degrees between contact sheet cells"),
+		stepLon:   flag.Float64("step-lon", 90.0, "Longitude change in degrees between contact sheet cells"),
+		stepAlt:   flag.Float64("step-alt", 0.0, "Altitude change in kilometers between contact sheet cells"),
+		stepTime:  flag.Duration("step-time", 0, "Time change between contact sheet cells (e.g. 1h, 30m)"),
+		captions:  flag.Bool("captions", false, "Caption each contact sheet cell with its position and time"),
+		cubemap:   flag.String("cubemap", "", "Render six cube faces from the camera position: cross (4x3 layout) or equirect (stitched 360° panorama)"),
 
-		panoramic: flag.Bool("panoramic", true, "Render a 2x2 panoramic view (90° apart in latitude)"),
+		animate:   flag.String("animate", "", "Render an animation from -time to -end into this directory as numbered PNG frames; existing frames are kept"),
+		end:       flag.String("end", "", "End time of the animation in RFC3339 format"),
+		step:      flag.Duration("step", time.Minute, "Time between animation frames (e.g. 10s, 1m)"),
+		keyframes: flag.String("keyframes", "", "Camera path file of time,lat,lon,alt,fov,tilt,yaw,roll lines; sets the animation range by default"),
+
+		renderTiles: flag.String("render-tiles", "", "Render the frame as tiles into this directory; run it in several processes to share the work"),
+		tileSize:    flag.Int("tile-size", 4096, "Edge length in pixels of -render-tiles tiles (a multiple of 256)"),
+		assemble:    flag.String("assemble", "", "Assemble the finished tiles in this directory into a tiled TIFF at -out (at most 4 GiB compressed)"),
 
 		showHelp: flag.Bool("h", false, "Show this help message"),
 	}
@@ -61,10 +151,14 @@ Usage:
 
 `, os.Args[0])
 
-	printGroup("Camera Options", []string{"lat", "lon", "alt", "fov", "tilt", "yaw"})
-	printGroup("Rendering Options", []string{"size", "supersample", "time", "panoramic"})
-	printGroup("Assets", []string{"day", "night", "clouds"})
-	printGroup("Output", []string{"out"})
+	printGroup("Camera Options", []string{"lat", "lon", "alt", "fov", "fov-axis", "projection", "tilt", "yaw", "roll", "target-lat", "target-lon", "target-alt", "attitude", "ra", "dec"})
+	printGroup("Orbit Options", []string{"tle", "tle-name", "elements", "eci", "epoch", "j2"})
+	printGroup("Rendering Options", []string{"size", "width", "height", "supersample", "crop", "time", "timeout", "cubemap"})
+	printGroup("Contact Sheet Options", []string{"panoramic", "grid", "step-lat", "step-lon", "step-alt", "step-time", "captions"})
+	printGroup("Animation Options", []string{"animate", "end", "step", "keyframes"})
+	printGroup("Tiled Rendering Options", []string{"render-tiles", "tile-size", "assemble"})
+	printGroup("Assets", []string{"day", "night", "clouds", "filter", "mipmaps", "texture-cache-mb", "cloud-frames", "cloud-motion"})
+	printGroup("Output", []string{"out", "quiet"})
 	printGroup("Misc", []string{"h"})
 }
 
@@ -72,7 +166,7 @@ func printGroup(title string, keys []string) {
 	fmt.Fprintf(os.Stderr, "%s:\n", title)
 	for _, name := range keys {
 		if f := flag.Lookup(name); f != nil {
-			fmt.Fprintf(os.Stderr, "  -%-10s %s (default %q)\n", f.Name, f.Usage, f.DefValue)
+			fmt.Fprintf(os.Stderr, "  -%-14s %s (default %q)\n", f.Name, f.Usage, f.DefValue)
 		}
 	}
 	fmt.Fprintln(os.Stderr)
@@ -87,94 +181,349 @@ func main() {
 		printHelp()
 		return
 	}
-	print("Generating " + *cfg.out + " ")
+	if *cfg.assemble != "" {
+		if err := assembleTiles(*cfg.assemble, *cfg.out, *cfg.quiet); err != nil {
+			log.Fatalf("Could not assemble tiles: %v", err)
+		}
+		return
+	}
 
 	renderTime := parseTimeOrExit(*cfg.timeStr)
 
+	filter, err := render.ParseFilter(*cfg.filter)
+	if err != nil {
+		log.Fatalf("Invalid -filter: %v", err)
+	}
 	theme := render.Theme{
-		DaySky:   colors.New(0.25, 0.60, 1.00, 0.5),
-		NightSky: colors.New(0.043, 0.047, 0.063, 0.5),
-		Warm:     colors.New(1.02, 1.0, 0.98, 1.0),
-		Day:      *cfg.day,
-		Night:    *cfg.night,
-		Clouds:   *cfg.clouds,
+		DaySky:         colors.New(0.25, 0.60, 1.00, 0.5),
+		NightSky:       colors.New(0.043, 0.047, 0.063, 0.5),
+		Warm:           colors.New(1.02, 1.0, 0.98, 1.0),
+		Day:            *cfg.day,
+		Night:          *cfg.night,
+		Clouds:         *cfg.clouds,
+		DayFilter:      filter,
+		NightFilter:    filter,
+		CloudsFilter:   filter,
+		Mipmaps:        *cfg.mipmaps,
+		TextureCacheMB: *cfg.textureCacheMB,
+		CloudMotion:    *cfg.cloudMotion,
+	}
+	if *cfg.cloudFrames != "" {
+		theme.CloudFrames = readCloudFramesOrExit(*cfg.cloudFrames)
+	} else if *cfg.cloudMotion {
+		log.Fatalf("-cloud-motion blends between -cloud-frames; give it a -cloud-frames file")
 	}
 
-	numWorkers := runtime.GOMAXPROCS(0)
-	sunDir := earth.SunDirectionECEF(renderTime)
+	var sources []string
+	for _, src := range []struct{ name, value string }{
+		{"-tle", *cfg.tle}, {"-elements", *cfg.elements}, {"-eci", *cfg.eci},
+	} {
+		if src.value != "" {
+			sources = append(sources, src.name)
+		}
+	}
+	if len(sources) > 1 {
+		log.Fatalf("%s each set the orbit; give only one of them", strings.Join(sources, " and "))
+	}
+	switch {
+	case *cfg.tle != "":
+		cfg.propagator = loadTLE(*cfg.tle, *cfg.tleName)
+	case *cfg.elements != "" || *cfg.eci != "":
+		cfg.propagator = newKeplerOrbit(cfg, renderTime)
+	}
+	if cfg.propagator != nil {
+		*cfg.lat, *cfg.lon, *cfg.alt = orbitPosition(cfg.propagator, renderTime)
+	}
+
+	renderer, err := render.NewRenderer(theme, runtime.GOMAXPROCS(0))
+	if err != nil {
+		log.Fatalf("Could not load textures: %v", err)
+	}
+	defer renderer.Close()
+
+	// Ctrl-C stops the render instead of killing it mid-frame, so an
+	// interrupted animation leaves no partial frame behind.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *cfg.timeout)
+		defer cancel()
+	}
+
+	if *cfg.renderTiles != "" {
+		if err := renderTiles(ctx, cfg, renderTime, renderer); err != nil {
+			log.Fatalf("Could not render tiles; %v", err)
+		}
+		reportCacheStats(cfg, renderer)
+		return
+	}
+
+	if *cfg.animate != "" {
+		if err := renderAnimation(ctx, cfg, renderTime, renderer); err != nil {
+			log.Fatalf("Could not render animation; %v", err)
+		}
+		reportCacheStats(cfg, renderer)
+		return
+	}
 
 	var img image.Image
-	var err error
-	if *cfg.panoramic {
-		img, err = renderPanoramic(cfg, sunDir, theme, numWorkers)
+	write := writePNG
+	if *cfg.crop != "" && (*cfg.cubemap != "" || *cfg.panoramic) {
+		log.Fatalf("-crop applies to single views and animations; use it with -panoramic=false")
+	}
+	switch {
+	case *cfg.cubemap != "":
+		img, err = renderCubeMap(ctx, cfg, renderTime, renderer)
+		if *cfg.cubemap == "equirect" {
+			write = writePanorama
+		}
+	case *cfg.panoramic:
+		img, err = renderContactSheet(ctx, cfg, renderTime, renderer)
+	default:
+		img, err = renderSingle(ctx, cfg, renderTime, renderer)
+	}
 
+	if err != nil {
+		log.Fatalf("Could not generate image; %v", err)
+	}
+
+	if err := write(*cfg.out, img); err != nil {
+		log.Fatalf("Failed to write image: %v", err)
+	}
+	reportCacheStats(cfg, renderer)
+}
+
+func renderSingle(ctx context.Context, cfg config, renderTime time.Time, renderer *render.Renderer) (image.Image, error) {
+	camera := newCamera(cfg, *cfg.lat, *cfg.lon, *cfg.alt, renderTime)
+	sunDir := earth.SunDirectionECEF(renderTime)
+	width, height := outputSize(cfg)
+	return renderer.Render(ctx, camera, sunDir, render.Options{
+		Width:         width,
+		Height:        height,
+		Supersampling: *cfg.supersample,
+		Progress:      newProgress(cfg, *cfg.out),
+		Crop:          cropRect(cfg),
+		Time:          renderTime,
+	})
+}
+
+// newCamera builds the camera at lat/lon/alt at time t. With -attitude the
+// pointing mode sets the orientation; otherwise it looks at the ground target
+// when -target-lat or -target-lon is given and toward Earth's center.
+func newCamera(cfg config, lat, lon, alt float64, t time.Time) render.Camera {
+	var camera render.Camera
+	if *cfg.attitude != "" {
+		camera = attitudeCamera(cfg, lat, lon, alt, t)
+	} else if isFlagSet("target-lat") || isFlagSet("target-lon") {
+		camera = render.NewLookAtCamera(lat, lon, alt, *cfg.targetLat, *cfg.targetLon, *cfg.targetAlt, *cfg.fov, *cfg.roll)
 	} else {
-		img, err = renderSingle(cfg, sunDir, theme, numWorkers)
+		camera = render.NewCamera(lat, lon, alt, *cfg.fov, *cfg.tilt, *cfg.yaw, *cfg.roll)
+	}
+	return withLens(cfg, camera)
+}
+
+// withLens applies -fov-axis and -projection to camera.
+func withLens(cfg config, camera render.Camera) render.Camera {
+	axis, err := render.ParseFOVAxis(*cfg.fovAxis)
+	if err != nil {
+		log.Fatalf("Invalid -fov-axis: %v", err)
+	}
+	camera.FOVAxis = axis
+
+	proj, err := render.ParseProjection(*cfg.projection)
+	if err != nil {
+		log.Fatalf("Invalid -projection: %v", err)
+	}
+	camera.Projection = proj
+	return camera
+}
+
+// attitudeCamera orients the camera with the -attitude pointing mode. The
+// velocity comes from the orbit, if any, so a static camera only supports
+// modes that don't need one.
+func attitudeCamera(cfg config, lat, lon, alt float64, t time.Time) render.Camera {
+	var provider attitude.Provider
+	switch *cfg.attitude {
+	case "nadir":
+		provider = attitude.Nadir{}
+	case "lvlh", "velocity":
+		provider = attitude.VelocityForward{}
+	case "inertial":
+		provider = attitude.InertialFixed{RA: *cfg.ra, Dec: *cfg.dec}
+	case "sun":
+		provider = attitude.SunPointing{}
+	case "target":
+		provider = attitude.TargetTracking{Lat: *cfg.targetLat, Lon: *cfg.targetLon, Alt: *cfg.targetAlt}
+	default:
+		log.Fatalf("Invalid -attitude %q (want nadir, lvlh, inertial, sun or target)", *cfg.attitude)
 	}
 
+	state := orbit.State{Time: t, Position: earth.PositionECEF(lat, lon, alt)}
+	if cfg.propagator != nil {
+		s, err := cfg.propagator.Propagate(t)
+		if err != nil {
+			log.Fatalf("Could not propagate orbit to %s: %v", t.Format(time.RFC3339), err)
+		}
+		state.Velocity = s.Velocity
+	}
+
+	b, err := provider.Attitude(state)
 	if err != nil {
-		log.Fatalf("Could not generate image; %w", err)
+		log.Fatalf("Could not orient camera: %v", err)
+	}
+	camera := render.NewCameraFromBasis(state.Position, b.Forward, b.Right, b.Up, *cfg.fov)
+	return camera.Rotate(*cfg.tilt, *cfg.yaw, *cfg.roll)
+}
+
+// cropRect parses -crop x,y,w,h; it is empty without -crop.
+func cropRect(cfg config) image.Rectangle {
+	if *cfg.crop == "" {
+		return image.Rectangle{}
+	}
+	v := parseFloatsOrExit("crop", *cfg.crop, 4)
+	x, y, w, h := int(v[0]), int(v[1]), int(v[2]), int(v[3])
+	if w <= 0 || h <= 0 || float64(x) != v[0] || float64(y) != v[1] || float64(w) != v[2] || float64(h) != v[3] {
+		log.Fatalf("Invalid -crop %q: want whole pixels x,y,w,h with a positive size", *cfg.crop)
 	}
+	return image.Rect(x, y, x+w, y+h)
+}
 
-	if err := writePNG(*cfg.out, img); err != nil {
-		log.Fatalf("Failed to write PNG: %v", err)
+// outputSize returns the output dimensions, with -width/-height falling back to -size.
+func outputSize(cfg config) (int, int) {
+	width, height := *cfg.size, *cfg.size
+	if *cfg.width > 0 {
+		width = *cfg.width
+	}
+	if *cfg.height > 0 {
+		height = *cfg.height
 	}
+	return width, height
 }
 
-func renderSingle(cfg config, sunDir vectors.Vec3, theme render.Theme, numWorkers int) (image.Image, error) {
-	camera := render.NewCamera(*cfg.lat, *cfg.lon, *cfg.alt, *cfg.fov, *cfg.tilt, *cfg.yaw)
-	return render.RenderScene(
-		camera,
-		sunDir,
-		*cfg.size,
-		*cfg.supersample,
-		theme,
-		numWorkers,
-	)
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
-func renderPanoramic(cfg config, sunDir vectors.Vec3, theme render.Theme, numWorkers int) (image.Image, error) {
-	canvasSize := *cfg.size
-	if canvasSize%2 != 0 {
-		log.Fatalf("size must be even for panoramic; got %d", canvasSize)
+// renderCubeMap renders the six cube faces around the camera, using -size as
+// the face size, and lays them out as a cross or stitches them into a 4:2
+// equirectangular panorama (-width/-height override its size).
+func renderCubeMap(ctx context.Context, cfg config, renderTime time.Time, renderer *render.Renderer) (image.Image, error) {
+	layout := *cfg.cubemap
+	if layout != "cross" && layout != "equirect" {
+		log.Fatalf("Invalid -cubemap %q (want cross or equirect)", layout)
+	}
+
+	camera := newCamera(cfg, *cfg.lat, *cfg.lon, *cfg.alt, renderTime)
+	sunDir := earth.SunDirectionECEF(renderTime)
+	faceSize := *cfg.size
+	faces, err := renderer.RenderCubeMap(ctx, camera, sunDir, renderTime, faceSize, *cfg.supersample, newProgress(cfg, *cfg.out))
+	if err != nil {
+		return nil, err
 	}
-	tileSize := canvasSize / 2
 
-	// Longitutes offset by 0, 90, 180, 270°
-	lons := []float64{
-		*cfg.lon + 0,
-		*cfg.lon + 90,
-		*cfg.lon + 180,
-		*cfg.lon + 270,
+	if layout == "cross" {
+		return render.CubeCross(faces), nil
+	}
+	width, height := 4*faceSize, 2*faceSize
+	if *cfg.width > 0 {
+		width = *cfg.width
+	}
+	if *cfg.height > 0 {
+		height = *cfg.height
 	}
+	return render.StitchEquirectangular(faces, width, height), nil
+}
 
-	tiles := make([]image.Image, 4)
-	for i, lon := range lons {
-		camera := render.NewCamera(*cfg.lat, lon, *cfg.alt, *cfg.fov, *cfg.tilt, *cfg.yaw)
-		img, err := render.RenderScene(
-			camera,
-			sunDir,
-			tileSize,
-			*cfg.supersample,
-			theme,
-			numWorkers,
-		)
-		if err != nil {
-			return nil, err
+// loadTLE reads the element sets in path and returns an SGP4 propagator for
+// the one matching name (by name or catalog number), or the first one.
+func loadTLE(path, name string) orbit.Propagator {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not open TLE file: %v", err)
+	}
+	defer f.Close()
+
+	tles, err := orbit.ReadTLEs(f)
+	if err != nil {
+		log.Fatalf("Could not read TLE file %s: %v", path, err)
+	}
+	for _, tle := range tles {
+		if name == "" || strings.EqualFold(tle.Name, name) || strconv.Itoa(tle.CatalogNumber) == name {
+			prop, err := orbit.NewSGP4(tle)
+			if err != nil {
+				log.Fatalf("Could not initialize SGP4 for %q: %v", tle.Name, err)
+			}
+			return prop
 		}
-		tiles[i] = img
 	}
+	log.Fatalf("No element set matching %q in %s", name, path)
+	return nil
+}
 
-	out := image.NewRGBA(image.Rect(0, 0, canvasSize, canvasSize))
-	positions := []image.Point{
-		{0, 0}, {tileSize, 0},
-		{0, tileSize}, {tileSize, tileSize},
+// newKeplerOrbit builds a two-body propagator from -elements or -eci.
+func newKeplerOrbit(cfg config, renderTime time.Time) orbit.Propagator {
+	epoch := renderTime
+	if *cfg.epoch != "" {
+		epoch = parseTimeOrExit(*cfg.epoch)
 	}
-	for i := 0; i < 4; i++ {
-		dst := image.Rectangle{Min: positions[i], Max: positions[i].Add(image.Point{tileSize, tileSize})}
-		draw.Draw(out, dst, tiles[i], image.Point{}, draw.Src)
+
+	var prop *orbit.Kepler
+	var err error
+	if *cfg.elements != "" {
+		v := parseFloatsOrExit("elements", *cfg.elements, 6)
+		prop, err = orbit.NewKepler(orbit.Elements{
+			SemiMajorAxis: v[0],
+			Eccentricity:  v[1],
+			Inclination:   v[2],
+			RAAN:          v[3],
+			ArgPerigee:    v[4],
+			TrueAnomaly:   v[5],
+			Epoch:         epoch,
+		}, *cfg.j2)
+	} else {
+		v := parseFloatsOrExit("eci", *cfg.eci, 6)
+		pos := vectors.Vec3{X: v[0], Y: v[1], Z: v[2]}
+		vel := vectors.Vec3{X: v[3], Y: v[4], Z: v[5]}
+		prop, err = orbit.NewKeplerFromStateECI(pos, vel, epoch, *cfg.j2)
+	}
+	if err != nil {
+		log.Fatalf("Invalid orbit: %v", err)
+	}
+	return prop
+}
+
+// parseFloatsOrExit parses a comma-separated list of exactly n numbers.
+func parseFloatsOrExit(name, s string, n int) []float64 {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		log.Fatalf("Invalid -%s: expected %d comma-separated values, got %d", name, n, len(parts))
+	}
+	out := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			log.Fatalf("Invalid -%s: %v", name, err)
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// orbitPosition returns the camera lat/lon (deg) and altitude (km) on the
+// propagated orbit at t.
+func orbitPosition(prop orbit.Propagator, t time.Time) (float64, float64, float64) {
+	state, err := prop.Propagate(t)
+	if err != nil {
+		log.Fatalf("Could not propagate orbit to %s: %v", t.Format(time.RFC3339), err)
 	}
-	return out, nil
+	return state.LatLonAlt()
 }
 
 func parseTimeOrExit(timeStr string) time.Time {
@@ -189,10 +538,39 @@ func parseTimeOrExit(timeStr string) time.Time {
 }
 
 func writePNG(path string, img image.Image) error {
-	f, err := os.Create(path)
+	f, err := createOutput(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	return (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(f, img)
 }
+
+// writePanorama writes an equirectangular panorama with spherical photo
+// metadata, as JPEG for .jpg/.jpeg paths and PNG otherwise.
+func writePanorama(path string, img image.Image) error {
+	f, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return render.EncodePanoramaJPEG(f, img, 95)
+	default:
+		return render.EncodePanoramaPNG(f, img)
+	}
+}
+
+// createOutput creates the file at path, or returns stdout for "-".
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,236 @@
+package orbit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// Earth gravity model constants (EGM96/WGS-84) for two-body propagation.
+const (
+	Mu               = 398600.4418 // km³/s²
+	EquatorialRadius = 6378.137    // km
+	J2               = 1.08262668e-3
+)
+
+// Elements are classical Keplerian orbital elements in the ECI frame used by
+// earth.ECIToECEF (equator and equinox of date). Angles are in degrees.
+type Elements struct {
+	SemiMajorAxis float64 // km
+	Eccentricity  float64
+	Inclination   float64
+	RAAN          float64 // right ascension of the ascending node
+	ArgPerigee    float64
+	TrueAnomaly   float64
+	Epoch         time.Time
+}
+
+// errElements is returned for orbits the two-body propagator cannot handle.
+var errElements = errors.New("kepler: only closed orbits (0 <= e < 1, a > 0) are supported")
+
+// StateECI returns the ECI position (km) and velocity (km/s) at the epoch.
+func (e Elements) StateECI() (vectors.Vec3, vectors.Vec3) {
+	const deg = math.Pi / 180.0
+	nu := e.TrueAnomaly * deg
+
+	p := e.SemiMajorAxis * (1 - e.Eccentricity*e.Eccentricity)
+	r := p / (1 + e.Eccentricity*math.Cos(nu))
+	vScale := math.Sqrt(Mu / p)
+
+	// Perifocal frame: x toward perigee, z along angular momentum.
+	posPF := vectors.Vec3{X: r * math.Cos(nu), Y: r * math.Sin(nu)}
+	velPF := vectors.Vec3{X: -vScale * math.Sin(nu), Y: vScale * (e.Eccentricity + math.Cos(nu))}
+
+	rot := perifocalToECI(e.RAAN*deg, e.Inclination*deg, e.ArgPerigee*deg)
+	return rot.MulVec(posPF), rot.MulVec(velPF)
+}
+
+// perifocalToECI returns R3(-raan)·R1(-inc)·R3(-argp).
+func perifocalToECI(raan, inc, argp float64) vectors.Mat3 {
+	cO, sO := math.Cos(raan), math.Sin(raan)
+	ci, si := math.Cos(inc), math.Sin(inc)
+	cw, sw := math.Cos(argp), math.Sin(argp)
+	return vectors.Mat3{
+		{cO*cw - sO*sw*ci, -cO*sw - sO*cw*ci, sO * si},
+		{sO*cw + cO*sw*ci, -sO*sw + cO*cw*ci, -cO * si},
+		{sw * si, cw * si, ci},
+	}
+}
+
+// ElementsFromStateECI converts an ECI position (km) and velocity (km/s) at
+// epoch into Keplerian elements. For circular orbits the argument of perigee
+// is 0 and the true anomaly is measured from the ascending node; for
+// equatorial orbits the RAAN is 0 and the node is taken along ECI +X.
+func ElementsFromStateECI(pos, vel vectors.Vec3, epoch time.Time) (Elements, error) {
+	const eps = 1e-10
+	r := pos.Norm()
+	v := vel.Norm()
+	if r == 0 {
+		return Elements{}, fmt.Errorf("kepler: zero position vector")
+	}
+
+	h := pos.Cross(vel)
+	if h.Norm() < eps {
+		return Elements{}, fmt.Errorf("kepler: degenerate (rectilinear) orbit")
+	}
+	hHat := h.Normalize()
+
+	energy := v*v/2 - Mu/r
+	if energy >= 0 {
+		return Elements{}, errElements
+	}
+	a := -Mu / (2 * energy)
+
+	eVec := pos.Scale(v*v - Mu/r).Sub(vel.Scale(pos.Dot(vel))).Scale(1 / Mu)
+	ecc := eVec.Norm()
+
+	inc := math.Acos(clamp(hHat.Z, -1, 1))
+
+	node := vectors.Vec3{Z: 1}.Cross(h)
+	raan := 0.0
+	if node.Norm() < eps {
+		node = vectors.Vec3{X: 1} // equatorial: measure from +X
+	} else {
+		node = node.Normalize()
+		raan = math.Atan2(node.Y, node.X)
+	}
+
+	periDir := node // circular: measure from the node
+	argp := 0.0
+	if ecc > eps {
+		periDir = eVec.Normalize()
+		argp = signedAngle(node, periDir, hHat)
+	}
+	nu := signedAngle(periDir, pos, hHat)
+
+	const toDeg = 180.0 / math.Pi
+	return Elements{
+		SemiMajorAxis: a,
+		Eccentricity:  ecc,
+		Inclination:   inc * toDeg,
+		RAAN:          wrapDeg(raan * toDeg),
+		ArgPerigee:    wrapDeg(argp * toDeg),
+		TrueAnomaly:   wrapDeg(nu * toDeg),
+		Epoch:         epoch,
+	}, nil
+}
+
+// Kepler propagates Keplerian elements analytically: exact two-body motion,
+// optionally with the secular J2 drift of the node, perigee and mean anomaly.
+type Kepler struct {
+	elements Elements
+	withJ2   bool
+
+	meanAnomaly0                float64 // rad
+	meanMotion                  float64 // rad/s
+	raanDot, argpDot, meanJ2Dot float64 // rad/s
+}
+
+// NewKepler returns a propagator for elements. With withJ2 the elements are
+// treated as mean elements and drift under Earth's oblateness.
+func NewKepler(elements Elements, withJ2 bool) (*Kepler, error) {
+	e := elements.Eccentricity
+	a := elements.SemiMajorAxis
+	if e < 0 || e >= 1 || a <= 0 {
+		return nil, errElements
+	}
+
+	k := &Kepler{elements: elements, withJ2: withJ2}
+	k.meanAnomaly0 = trueToMean(elements.TrueAnomaly*math.Pi/180.0, e)
+	k.meanMotion = math.Sqrt(Mu / (a * a * a))
+
+	if withJ2 {
+		p := a * (1 - e*e)
+		cosI := math.Cos(elements.Inclination * math.Pi / 180.0)
+		f := J2 * (EquatorialRadius / p) * (EquatorialRadius / p) * k.meanMotion
+		k.raanDot = -1.5 * f * cosI
+		k.argpDot = 0.75 * f * (5*cosI*cosI - 1)
+		k.meanJ2Dot = 0.75 * f * math.Sqrt(1-e*e) * (3*cosI*cosI - 1)
+	}
+	return k, nil
+}
+
+// NewKeplerFromStateECI returns a propagator for the orbit through an ECI
+// position (km) and velocity (km/s) at epoch.
+func NewKeplerFromStateECI(pos, vel vectors.Vec3, epoch time.Time, withJ2 bool) (*Kepler, error) {
+	elements, err := ElementsFromStateECI(pos, vel, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return NewKepler(elements, withJ2)
+}
+
+// ElementsAt returns the osculating (or, with J2, mean) elements at t.
+func (k *Kepler) ElementsAt(t time.Time) Elements {
+	dt := t.Sub(k.elements.Epoch).Seconds()
+	e := k.elements.Eccentricity
+
+	const toDeg = 180.0 / math.Pi
+	m := k.meanAnomaly0 + (k.meanMotion+k.meanJ2Dot)*dt
+
+	out := k.elements
+	out.Epoch = t
+	out.RAAN = wrapDeg(k.elements.RAAN + k.raanDot*dt*toDeg)
+	out.ArgPerigee = wrapDeg(k.elements.ArgPerigee + k.argpDot*dt*toDeg)
+	out.TrueAnomaly = wrapDeg(meanToTrue(m, e) * toDeg)
+	return out
+}
+
+// PropagateECI returns the ECI position (km) and velocity (km/s) at t.
+func (k *Kepler) PropagateECI(t time.Time) (vectors.Vec3, vectors.Vec3) {
+	return k.ElementsAt(t).StateECI()
+}
+
+// Propagate returns the ECEF state at t.
+func (k *Kepler) Propagate(t time.Time) (State, error) {
+	pos, vel := k.PropagateECI(t)
+	pos, vel = earth.ECIToECEFState(pos, vel, t)
+	return State{Time: t, Position: pos, Velocity: vel}, nil
+}
+
+// trueToMean converts true anomaly to mean anomaly (radians).
+func trueToMean(nu, e float64) float64 {
+	E := 2 * math.Atan2(math.Sqrt(1-e)*math.Sin(nu/2), math.Sqrt(1+e)*math.Cos(nu/2))
+	return E - e*math.Sin(E)
+}
+
+// meanToTrue solves Kepler's equation M = E - e·sin(E) and returns the true
+// anomaly (radians).
+func meanToTrue(m, e float64) float64 {
+	m = math.Mod(m, 2*math.Pi)
+	E := m
+	if e > 0.8 {
+		E = math.Pi
+	}
+	for i := 0; i < 50; i++ {
+		d := (E - e*math.Sin(E) - m) / (1 - e*math.Cos(E))
+		E -= d
+		if math.Abs(d) < 1e-14 {
+			break
+		}
+	}
+	return 2 * math.Atan2(math.Sqrt(1+e)*math.Sin(E/2), math.Sqrt(1-e)*math.Cos(E/2))
+}
+
+// signedAngle returns the angle from a to b (radians), positive about axis.
+func signedAngle(a, b, axis vectors.Vec3) float64 {
+	return math.Atan2(a.Cross(b).Dot(axis), a.Dot(b))
+}
+
+// wrapDeg wraps an angle into [0, 360).
+func wrapDeg(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// clamp clamps x into [lo, hi].
+func clamp(x, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, x))
+}
@@ -0,0 +1,74 @@
+package orbit
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+func TestElementsRoundTrip(t *testing.T) {
+	epoch := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	in := Elements{
+		SemiMajorAxis: 7200,
+		Eccentricity:  0.05,
+		Inclination:   63.4,
+		RAAN:          120,
+		ArgPerigee:    270,
+		TrueAnomaly:   45,
+		Epoch:         epoch,
+	}
+	pos, vel := in.StateECI()
+	out, err := ElementsFromStateECI(pos, vel, epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-8 }
+	if !near(out.SemiMajorAxis, in.SemiMajorAxis) || !near(out.Eccentricity, in.Eccentricity) ||
+		!near(out.Inclination, in.Inclination) || !near(out.RAAN, in.RAAN) ||
+		!near(out.ArgPerigee, in.ArgPerigee) || !near(out.TrueAnomaly, in.TrueAnomaly) {
+		t.Fatalf("round trip mismatch:\n in  %+v\n out %+v", in, out)
+	}
+}
+
+func TestKeplerPropagation(t *testing.T) {
+	epoch := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	el := Elements{SemiMajorAxis: 7000, Eccentricity: 0.01, Inclination: 98, RAAN: 10, ArgPerigee: 30, TrueAnomaly: 0, Epoch: epoch}
+
+	// Two-body: the state repeats after one period.
+	k, err := NewKepler(el, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := time.Duration(2 * math.Pi * math.Sqrt(math.Pow(el.SemiMajorAxis, 3)/Mu) * float64(time.Second))
+	p0, v0 := k.PropagateECI(epoch)
+	p1, v1 := k.PropagateECI(epoch.Add(period))
+	if vectors.Distance(p0, p1) > 1e-3 || vectors.Distance(v0, v1) > 1e-6 {
+		t.Fatalf("orbit did not close after one period: %+v vs %+v", p0, p1)
+	}
+
+	// A state vector reproduces the same orbit.
+	fromState, err := NewKeplerFromStateECI(p0, v0, epoch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := epoch.Add(37 * time.Minute)
+	pa, _ := k.PropagateECI(at)
+	pb, _ := fromState.PropagateECI(at)
+	if vectors.Distance(pa, pb) > 1e-6 {
+		t.Fatalf("state-vector orbit diverges: %+v vs %+v", pa, pb)
+	}
+
+	// J2: a ~98° orbit at 7000 km regresses its node eastward by about
+	// 1°/day (sun-synchronous).
+	kj2, err := NewKepler(el, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dRAAN := kj2.ElementsAt(epoch.Add(24*time.Hour)).RAAN - el.RAAN
+	if dRAAN < 0.8 || dRAAN > 1.2 {
+		t.Fatalf("J2 node drift = %.3f°/day, want about +1", dRAAN)
+	}
+}
@@ -0,0 +1,28 @@
+// Package orbit propagates spacecraft orbits and reports their state in the
+// same ECEF frame the renderer uses.
+package orbit
+
+import (
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// State is a spacecraft position (km) and velocity (km/s) in ECEF at Time.
+type State struct {
+	Time     time.Time
+	Position vectors.Vec3
+	Velocity vectors.Vec3
+}
+
+// LatLonAlt returns the sub-satellite lat/lon (deg) and altitude (km) on the
+// renderer's spherical Earth.
+func (s State) LatLonAlt() (float64, float64, float64) {
+	return earth.LatLonAlt(s.Position)
+}
+
+// Propagator computes a spacecraft state at a given time.
+type Propagator interface {
+	Propagate(t time.Time) (State, error)
+}
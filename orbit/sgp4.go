@@ -0,0 +1,318 @@
+package orbit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// WGS-72 constants, as used to generate TLEs.
+const (
+	wgs72Radius = 6378.135 // km
+	wgs72Mu     = 398600.8 // km³/s²
+	wgs72J2     = 0.001082616
+	wgs72J3     = -0.00000253881
+	wgs72J4     = -0.00000165597
+	wgs72J3OJ2  = wgs72J3 / wgs72J2
+	twoPi       = 2 * math.Pi
+	minPerDay   = 1440.0
+)
+
+// xke is sqrt(mu) in Earth radii^1.5 per minute.
+var xke = 60.0 / math.Sqrt(wgs72Radius*wgs72Radius*wgs72Radius/wgs72Mu)
+
+// ErrDeepSpace is returned for element sets with an orbital period of 225
+// minutes or more, which need the SDP4 deep-space model.
+var ErrDeepSpace = errors.New("sgp4: deep-space orbits (period >= 225 min) are not supported")
+
+// ErrDecayed is returned when the propagated orbit has re-entered.
+var ErrDecayed = errors.New("sgp4: satellite has decayed")
+
+// SGP4 propagates a TLE with the near-Earth SGP4 model (Vallado et al.,
+// "Revisiting Spacetrack Report #3", 2006).
+type SGP4 struct {
+	tle TLE
+
+	// mean elements at epoch (radians, radians/minute)
+	ecco, inclo, nodeo, argpo, mo, bstar float64
+	noUnkozai                            float64
+
+	isimp                                      bool
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4    float64
+	delmo, eta, argpdot, omgcof, sinmao        float64
+	t2cof, t3cof, t4cof, t5cof, x1mth2, x7thm1 float64
+	mdot, nodedot, xlcof, xmcof, nodecf        float64
+}
+
+// NewSGP4 initializes the propagator for an element set.
+func NewSGP4(tle TLE) (*SGP4, error) {
+	const deg = math.Pi / 180.0
+	s := &SGP4{
+		tle:   tle,
+		ecco:  tle.Eccentricity,
+		inclo: tle.Inclination * deg,
+		nodeo: tle.RAAN * deg,
+		argpo: tle.ArgPerigee * deg,
+		mo:    tle.MeanAnomaly * deg,
+		bstar: tle.BStar,
+	}
+	noKozai := tle.MeanMotion * twoPi / minPerDay
+	if noKozai <= 0 {
+		return nil, fmt.Errorf("sgp4: invalid mean motion %v", tle.MeanMotion)
+	}
+	if s.ecco < 0 || s.ecco >= 1 {
+		return nil, fmt.Errorf("sgp4: invalid eccentricity %v", s.ecco)
+	}
+
+	// Recover the original mean motion and semi-major axis (initl).
+	eccsq := s.ecco * s.ecco
+	omeosq := 1 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(s.inclo)
+	cosio2 := cosio * cosio
+
+	ak := math.Pow(xke/noKozai, 2.0/3.0)
+	d1 := 0.75 * wgs72J2 * (3*cosio2 - 1) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1 - del*del - del*(1.0/3.0+134*del*del/81))
+	del = d1 / (adel * adel)
+	s.noUnkozai = noKozai / (1 + del)
+
+	if twoPi/s.noUnkozai >= 225 {
+		return nil, ErrDeepSpace
+	}
+
+	ao := math.Pow(xke/s.noUnkozai, 2.0/3.0)
+	sinio := math.Sin(s.inclo)
+	po := ao * omeosq
+	con42 := 1 - 5*cosio2
+	s.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1 - s.ecco)
+
+	if rp < 1 {
+		return nil, ErrDecayed
+	}
+
+	// Atmospheric density parameters, adjusted for low perigees.
+	ss := 78/wgs72Radius + 1
+	qzms2t := math.Pow((120-78)/wgs72Radius, 4)
+	s.isimp = rp < 220/wgs72Radius+1
+
+	sfour := ss
+	qzms24 := qzms2t
+	perige := (rp - 1) * wgs72Radius
+	if perige < 156 {
+		sfour = perige - 78
+		if perige < 98 {
+			sfour = 20
+		}
+		qzms24 = math.Pow((120-sfour)/wgs72Radius, 4)
+		sfour = sfour/wgs72Radius + 1
+	}
+
+	pinvsq := 1 / posq
+	tsi := 1 / (ao - sfour)
+	s.eta = ao * s.ecco * tsi
+	etasq := s.eta * s.eta
+	eeta := s.ecco * s.eta
+	psisq := math.Abs(1 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * s.noUnkozai * (ao*(1+1.5*etasq+eeta*(4+etasq)) +
+		0.375*wgs72J2*tsi/psisq*s.con41*(8+3*etasq*(8+etasq)))
+	s.cc1 = s.bstar * cc2
+	cc3 := 0.0
+	if s.ecco > 1e-4 {
+		cc3 = -2 * coef * tsi * wgs72J3OJ2 * s.noUnkozai * sinio / s.ecco
+	}
+	s.x1mth2 = 1 - cosio2
+	s.cc4 = 2 * s.noUnkozai * coef1 * ao * omeosq *
+		(s.eta*(2+0.5*etasq) + s.ecco*(0.5+2*etasq) -
+			wgs72J2*tsi/(ao*psisq)*(-3*s.con41*(1-2*eeta+etasq*(1.5-0.5*eeta))+
+				0.75*s.x1mth2*(2*etasq-eeta*(1+etasq))*math.Cos(2*s.argpo)))
+	s.cc5 = 2 * coef1 * ao * omeosq * (1 + 2.75*(etasq+eeta) + eeta*etasq)
+
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * wgs72J2 * pinvsq * s.noUnkozai
+	temp2 := 0.5 * temp1 * wgs72J2 * pinvsq
+	temp3 := -0.46875 * wgs72J4 * pinvsq * pinvsq * s.noUnkozai
+	s.mdot = s.noUnkozai + 0.5*temp1*rteosq*s.con41 + 0.0625*temp2*rteosq*(13-78*cosio2+137*cosio4)
+	s.argpdot = -0.5*temp1*con42 + 0.0625*temp2*(7-114*cosio2+395*cosio4) + temp3*(3-36*cosio2+49*cosio4)
+	xhdot1 := -temp1 * cosio
+	s.nodedot = xhdot1 + (0.5*temp2*(4-19*cosio2)+2*temp3*(3-7*cosio2))*cosio
+	s.omgcof = s.bstar * cc3 * math.Cos(s.argpo)
+	if s.ecco > 1e-4 {
+		s.xmcof = -2.0 / 3.0 * coef * s.bstar / eeta
+	}
+	s.nodecf = 3.5 * omeosq * xhdot1 * s.cc1
+	s.t2cof = 1.5 * s.cc1
+	if math.Abs(cosio+1) > 1.5e-12 {
+		s.xlcof = -0.25 * wgs72J3OJ2 * sinio * (3 + 5*cosio) / (1 + cosio)
+	} else {
+		s.xlcof = -0.25 * wgs72J3OJ2 * sinio * (3 + 5*cosio) / 1.5e-12
+	}
+	s.aycof = -0.5 * wgs72J3OJ2 * sinio
+	s.delmo = math.Pow(1+s.eta*math.Cos(s.mo), 3)
+	s.sinmao = math.Sin(s.mo)
+	s.x7thm1 = 7*cosio2 - 1
+
+	if !s.isimp {
+		cc1sq := s.cc1 * s.cc1
+		s.d2 = 4 * ao * tsi * cc1sq
+		temp := s.d2 * tsi * s.cc1 / 3
+		s.d3 = (17*ao + sfour) * temp
+		s.d4 = 0.5 * temp * ao * tsi * (221*ao + 31*sfour) * s.cc1
+		s.t3cof = s.d2 + 2*cc1sq
+		s.t4cof = 0.25 * (3*s.d3 + s.cc1*(12*s.d2+10*cc1sq))
+		s.t5cof = 0.2 * (3*s.d4 + 12*s.cc1*s.d3 + 6*s.d2*s.d2 + 15*cc1sq*(2*s.d2+cc1sq))
+	}
+	return s, nil
+}
+
+// TLE returns the element set the propagator was built from.
+func (s *SGP4) TLE() TLE {
+	return s.tle
+}
+
+// Propagate returns the ECEF state at t, rotated from TEME by mean sidereal
+// time.
+func (s *SGP4) Propagate(t time.Time) (State, error) {
+	pos, vel, err := s.PropagateTEME(t)
+	if err != nil {
+		return State{}, err
+	}
+	pos, vel = earth.TEMEToECEFState(pos, vel, t)
+	return State{Time: t, Position: pos, Velocity: vel}, nil
+}
+
+// PropagateTEME returns position (km) and velocity (km/s) at t in the
+// True Equator Mean Equinox frame that SGP4 works in.
+func (s *SGP4) PropagateTEME(t time.Time) (vectors.Vec3, vectors.Vec3, error) {
+	return s.propagate(t.Sub(s.tle.Epoch).Minutes())
+}
+
+func (s *SGP4) propagate(tsince float64) (vectors.Vec3, vectors.Vec3, error) {
+	// Secular gravity and atmospheric drag.
+	xmdf := s.mo + s.mdot*tsince
+	argpdf := s.argpo + s.argpdot*tsince
+	nodedf := s.nodeo + s.nodedot*tsince
+	argpm := argpdf
+	mm := xmdf
+	t2 := tsince * tsince
+	nodem := nodedf + s.nodecf*t2
+	tempa := 1 - s.cc1*tsince
+	tempe := s.bstar * s.cc4 * tsince
+	templ := s.t2cof * t2
+
+	if !s.isimp {
+		delomg := s.omgcof * tsince
+		delmtemp := 1 + s.eta*math.Cos(xmdf)
+		delm := s.xmcof * (delmtemp*delmtemp*delmtemp - s.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * tsince
+		t4 := t3 * tsince
+		tempa = tempa - s.d2*t2 - s.d3*t3 - s.d4*t4
+		tempe = tempe + s.bstar*s.cc5*(math.Sin(mm)-s.sinmao)
+		templ = templ + s.t3cof*t3 + t4*(s.t4cof+tsince*s.t5cof)
+	}
+
+	nm := s.noUnkozai
+	em := s.ecco
+	inclm := s.inclo
+
+	am := math.Pow(xke/nm, 2.0/3.0) * tempa * tempa
+	nm = xke / math.Pow(am, 1.5)
+	em -= tempe
+	if em >= 1 || em < -0.001 || am < 0.95 {
+		return vectors.Vec3{}, vectors.Vec3{}, fmt.Errorf("sgp4: elements diverged at %.1f min (e=%g, a=%g)", tsince, em, am)
+	}
+	if em < 1e-6 {
+		em = 1e-6
+	}
+	mm += s.noUnkozai * templ
+	xlm := mm + argpm + nodem
+
+	nodem = math.Mod(nodem, twoPi)
+	argpm = math.Mod(argpm, twoPi)
+	xlm = math.Mod(xlm, twoPi)
+	mm = math.Mod(xlm-argpm-nodem, twoPi)
+
+	sinim := math.Sin(inclm)
+	cosim := math.Cos(inclm)
+
+	// Long-period periodics.
+	axnl := em * math.Cos(argpm)
+	temp := 1 / (am * (1 - em*em))
+	aynl := em*math.Sin(argpm) + temp*s.aycof
+	xl := mm + argpm + nodem + temp*s.xlcof*axnl
+
+	// Solve Kepler's equation.
+	u := math.Mod(xl-nodem, twoPi)
+	eo1 := u
+	tem5 := 9999.9
+	var sineo1, coseo1 float64
+	for ktr := 1; math.Abs(tem5) >= 1e-12 && ktr <= 10; ktr++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = 1 - coseo1*axnl - sineo1*aynl
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / tem5
+		if math.Abs(tem5) >= 0.95 {
+			tem5 = math.Copysign(0.95, tem5)
+		}
+		eo1 += tem5
+	}
+
+	// Short-period periodics.
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1 - el2)
+	if pl < 0 {
+		return vectors.Vec3{}, vectors.Vec3{}, fmt.Errorf("sgp4: semi-latus rectum < 0 at %.1f min", tsince)
+	}
+	rl := am * (1 - ecose)
+	rdotl := math.Sqrt(am) * esine / rl
+	rvdotl := math.Sqrt(pl) / rl
+	betal := math.Sqrt(1 - el2)
+	temp = esine / (1 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1 - 2*sinu*sinu
+	temp = 1 / pl
+	temp1 := 0.5 * wgs72J2 * temp
+	temp2 := temp1 * temp
+
+	mrt := rl*(1-1.5*temp2*betal*s.con41) + 0.5*temp1*s.x1mth2*cos2u
+	su -= 0.25 * temp2 * s.x7thm1 * sin2u
+	xnode := nodem + 1.5*temp2*cosim*sin2u
+	xinc := inclm + 1.5*temp2*cosim*sinim*cos2u
+	mvt := rdotl - nm*temp1*s.x1mth2*sin2u/xke
+	rvdot := rvdotl + nm*temp1*(s.x1mth2*cos2u+1.5*s.con41)/xke
+
+	if mrt < 1 {
+		return vectors.Vec3{}, vectors.Vec3{}, ErrDecayed
+	}
+
+	// Orientation vectors.
+	sinsu, cossu := math.Sin(su), math.Cos(su)
+	snod, cnod := math.Sin(xnode), math.Cos(xnode)
+	sini, cosi := math.Sin(xinc), math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	uvec := vectors.Vec3{X: xmx*sinsu + cnod*cossu, Y: xmy*sinsu + snod*cossu, Z: sini * sinsu}
+	vvec := vectors.Vec3{X: xmx*cossu - cnod*sinsu, Y: xmy*cossu - snod*sinsu, Z: sini * cossu}
+
+	vkmpersec := wgs72Radius * xke / 60.0
+	pos := uvec.Scale(mrt * wgs72Radius)
+	vel := uvec.Scale(mvt).Add(vvec.Scale(rvdot)).Scale(vkmpersec)
+	return pos, vel, nil
+}
@@ -0,0 +1,60 @@
+package orbit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// Reference vectors from the SGP4 verification set (Vallado et al. 2006,
+// tcppver.out).
+const vanguard = `VANGUARD 1
+1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753
+2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667
+`
+
+func TestSGP4Vanguard(t *testing.T) {
+	tles, err := ReadTLEs(strings.NewReader(vanguard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tles) != 1 || tles[0].Name != "VANGUARD 1" || tles[0].CatalogNumber != 5 {
+		t.Fatalf("unexpected TLEs: %+v", tles)
+	}
+	prop, err := NewSGP4(tles[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		minutes  float64
+		pos, vel vectors.Vec3
+	}{
+		{0, vectors.Vec3{X: 7022.46529266, Y: -1400.08296755, Z: 0.03995155}, vectors.Vec3{X: 1.893841015, Y: 6.405893759, Z: 4.534807250}},
+		{360, vectors.Vec3{X: -7154.03120202, Y: -3783.17682504, Z: -3536.19412294}, vectors.Vec3{X: 4.741887409, Y: -4.151817765, Z: -2.093935425}},
+		{720, vectors.Vec3{X: -7134.59340119, Y: 6531.68641334, Z: 3260.27186483}, vectors.Vec3{X: -4.113793027, Y: -2.911922039, Z: -2.557327851}},
+	}
+	for _, c := range cases {
+		at := tles[0].Epoch.Add(time.Duration(c.minutes * float64(time.Minute)))
+		pos, vel, err := prop.PropagateTEME(at)
+		if err != nil {
+			t.Fatalf("t=%v: %v", c.minutes, err)
+		}
+		if d := vectors.Distance(pos, c.pos); d > 1e-3 {
+			t.Errorf("t=%v: position off by %.6f km: %+v", c.minutes, d, pos)
+		}
+		if d := vectors.Distance(vel, c.vel); d > 1e-6 {
+			t.Errorf("t=%v: velocity off by %.9f km/s: %+v", c.minutes, d, vel)
+		}
+	}
+}
+
+func TestParseTLEChecksum(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(vanguard), "\n")
+	bad := lines[1][:68] + "0"
+	if _, err := ParseTLE("", bad, lines[2]); err == nil {
+		t.Fatal("expected checksum error")
+	}
+}
@@ -0,0 +1,218 @@
+package orbit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLE holds the mean elements of a NORAD two-line element set. Angles are in
+// degrees, mean motion in revolutions per day.
+type TLE struct {
+	Name          string
+	CatalogNumber int
+	Epoch         time.Time
+
+	MeanMotionDot  float64 // first derivative of mean motion / 2 (rev/day²)
+	MeanMotionDDot float64 // second derivative of mean motion / 6 (rev/day³)
+	BStar          float64 // drag term (1/Earth radii)
+
+	Inclination  float64
+	RAAN         float64
+	Eccentricity float64
+	ArgPerigee   float64
+	MeanAnomaly  float64
+	MeanMotion   float64
+	RevNumber    int
+}
+
+// ParseTLE parses the two data lines of an element set. name may be empty.
+func ParseTLE(name, line1, line2 string) (TLE, error) {
+	line1 = strings.TrimRight(line1, " \r\n")
+	line2 = strings.TrimRight(line2, " \r\n")
+	if len(line1) < 68 || line1[0] != '1' {
+		return TLE{}, fmt.Errorf("tle: line 1 is malformed: %q", line1)
+	}
+	if len(line2) < 68 || line2[0] != '2' {
+		return TLE{}, fmt.Errorf("tle: line 2 is malformed: %q", line2)
+	}
+	for i, line := range []string{line1, line2} {
+		if len(line) >= 69 {
+			if err := verifyChecksum(line); err != nil {
+				return TLE{}, fmt.Errorf("tle: line %d: %w", i+1, err)
+			}
+		}
+	}
+
+	p := fieldParser{}
+	t := TLE{Name: strings.TrimSpace(name)}
+
+	t.CatalogNumber = p.int(line1, 2, 7, "catalog number")
+	year := p.int(line1, 18, 20, "epoch year")
+	day := p.float(line1, 20, 32, "epoch day")
+	t.MeanMotionDot = p.float(line1, 33, 43, "mean motion derivative")
+	t.MeanMotionDDot = p.exp(line1, 44, 52, "mean motion second derivative")
+	t.BStar = p.exp(line1, 53, 61, "bstar")
+
+	if catalog := p.int(line2, 2, 7, "catalog number"); p.err == nil && catalog != t.CatalogNumber {
+		return TLE{}, fmt.Errorf("tle: catalog numbers differ between lines (%d, %d)", t.CatalogNumber, catalog)
+	}
+	t.Inclination = p.float(line2, 8, 16, "inclination")
+	t.RAAN = p.float(line2, 17, 25, "right ascension of ascending node")
+	t.Eccentricity = p.float(line2, 26, 33, "eccentricity") / 1e7
+	t.ArgPerigee = p.float(line2, 34, 42, "argument of perigee")
+	t.MeanAnomaly = p.float(line2, 43, 51, "mean anomaly")
+	t.MeanMotion = p.float(line2, 52, 63, "mean motion")
+	t.RevNumber = p.int(line2, 63, 68, "revolution number")
+	if p.err != nil {
+		return TLE{}, p.err
+	}
+
+	// Two-digit years: 57-99 are 1957-1999, 00-56 are 2000-2056.
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	dayNanos := math.Round((day - 1) * 24 * float64(time.Hour))
+	t.Epoch = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(dayNanos))
+
+	return t, nil
+}
+
+// ReadTLEs reads element sets in two-line or three-line (name line first)
+// format. Blank lines are skipped.
+func ReadTLEs(r io.Reader) ([]TLE, error) {
+	var out []TLE
+	var name string
+	var line1 string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, "1 ") && line1 == "":
+			line1 = line
+		case strings.HasPrefix(line, "2 ") && line1 != "":
+			tle, err := ParseTLE(name, line1, line)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tle)
+			name, line1 = "", ""
+		default:
+			if line1 != "" {
+				return nil, fmt.Errorf("tle: expected line 2 after %q, got %q", line1, line)
+			}
+			name = strings.TrimPrefix(line, "0 ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line1 != "" {
+		return nil, fmt.Errorf("tle: missing line 2 after %q", line1)
+	}
+	return out, nil
+}
+
+// verifyChecksum checks the modulo-10 checksum in column 69: the sum of all
+// digits, with '-' counting as 1.
+func verifyChecksum(line string) error {
+	sum := 0
+	for _, c := range line[:68] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	want := int(line[68] - '0')
+	if sum%10 != want {
+		return fmt.Errorf("checksum mismatch: computed %d, line says %d", sum%10, want)
+	}
+	return nil
+}
+
+// fieldParser extracts fixed-column fields, keeping the first error.
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) field(line string, from, to int) string {
+	return strings.TrimSpace(line[from:to])
+}
+
+func (p *fieldParser) fail(what, s string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("tle: invalid %s %q: %w", what, s, err)
+	}
+}
+
+func (p *fieldParser) int(line string, from, to int, what string) int {
+	s := p.field(line, from, to)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		p.fail(what, s, err)
+	}
+	return v
+}
+
+func (p *fieldParser) float(line string, from, to int, what string) float64 {
+	s := p.field(line, from, to)
+	if s == "" {
+		return 0
+	}
+	// Eccentricity and some derivatives omit the leading "0".
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "-.") || strings.HasPrefix(s, "+.") {
+		s = strings.Replace(s, ".", "0.", 1)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		p.fail(what, s, err)
+	}
+	return v
+}
+
+// exp parses the "assumed decimal point" notation, e.g. " 28098-4" is
+// 0.28098e-4.
+func (p *fieldParser) exp(line string, from, to int, what string) float64 {
+	s := p.field(line, from, to)
+	if s == "" {
+		return 0
+	}
+	sign := 1.0
+	switch s[0] {
+	case '-':
+		sign = -1
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	i := strings.LastIndexAny(s, "+-")
+	if i <= 0 {
+		p.fail(what, s, fmt.Errorf("missing exponent"))
+		return 0
+	}
+	mantissa, err := strconv.ParseFloat("0."+s[:i], 64)
+	if err != nil {
+		p.fail(what, s, err)
+		return 0
+	}
+	exponent, err := strconv.Atoi(s[i:])
+	if err != nil {
+		p.fail(what, s, err)
+		return 0
+	}
+	return sign * mantissa * math.Pow(10, float64(exponent))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/render"
+)
+
+// progressBar draws render progress as a single, redrawn line:
+//
+//	earth_view.png [###############               ]  50%  0:04 left
+type progressBar struct {
+	w     io.Writer
+	label string
+}
+
+// newProgress returns a progress bar on stderr labelled label, or nil with
+// -quiet.
+func newProgress(cfg config, label string) render.ProgressObserver {
+	if *cfg.quiet {
+		return nil
+	}
+	return &progressBar{w: os.Stderr, label: label}
+}
+
+func (b *progressBar) RenderProgress(p render.Progress) {
+	const width = 30
+	filled := int(p.Fraction() * width)
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", width-filled)
+
+	if p.Done == p.Total {
+		fmt.Fprintf(b.w, "\r%s [%s] 100%% %5s total\n", b.label, bar, formatDuration(p.Elapsed))
+		return
+	}
+	fmt.Fprintf(b.w, "\r%s [%s] %3.0f%% %5s left ", b.label, bar, 100*p.Fraction(), formatDuration(p.ETA))
+}
+
+// formatDuration formats d as m:ss, or h:mm:ss from an hour up.
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// reportCacheStats prints the texture tile cache statistics of the run to
+// stderr, unless -quiet or no texture went through the cache.
+func reportCacheStats(cfg config, renderer *render.Renderer) {
+	cache := renderer.TextureCache()
+	if *cfg.quiet || cache == nil {
+		return
+	}
+	st := cache.Stats()
+	if st.Hits+st.Misses == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Texture cache: %d hits, %d misses (%.1f%% hit rate), %d evictions, %.0f of %.0f MB in use\n",
+		st.Hits, st.Misses, 100*st.HitRate(), st.Evictions, float64(st.Bytes)/(1<<20), float64(st.Budget)/(1<<20))
+}
@@ -1,41 +1,101 @@
 package render
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/echoflaresat/spacecam/earth"
 	"github.com/echoflaresat/spacecam/vectors"
 )
 
+// FOVAxis selects which image dimension the camera field of view spans.
+type FOVAxis int
+
+const (
+	FOVHorizontal FOVAxis = iota // FOV spans the image width
+	FOVVertical                  // FOV spans the image height
+	FOVDiagonal                  // FOV spans the image diagonal
+)
+
+// ParseFOVAxis parses "horizontal", "vertical" or "diagonal" (or h, v, d).
+func ParseFOVAxis(s string) (FOVAxis, error) {
+	switch s {
+	case "horizontal", "h":
+		return FOVHorizontal, nil
+	case "vertical", "v":
+		return FOVVertical, nil
+	case "diagonal", "d":
+		return FOVDiagonal, nil
+	}
+	return 0, fmt.Errorf("unknown FOV axis %q (want horizontal, vertical or diagonal)", s)
+}
+
+func (a FOVAxis) String() string {
+	switch a {
+	case FOVHorizontal:
+		return "horizontal"
+	case FOVVertical:
+		return "vertical"
+	case FOVDiagonal:
+		return "diagonal"
+	}
+	return fmt.Sprintf("FOVAxis(%d)", int(a))
+}
+
 // Camera models a pinhole camera in ECEF coordinates.
 type Camera struct {
 	FOVDeg     float64
 	TanHalfFOV float64
+	FOVAxis    FOVAxis    // image axis FOVDeg applies to; the other follows from the aspect ratio
+	Projection Projection // maps pixels to rays; nil means Pinhole
 	Position   vectors.Vec3
 	Forward    vectors.Vec3
 	Right      vectors.Vec3
 	Up         vectors.Vec3
 }
 
-// NewCamera constructs a camera from geodetic lat/lon (deg), altitude (km),
-// field of view (deg), an additional tilt about the camera's Right axis (deg).
-func NewCamera(latDeg, lonDeg, altKm, fovDeg, tiltDeg, yawDeg float64) Camera {
-	lat := latDeg * math.Pi / 180.0
-	lon := lonDeg * math.Pi / 180.0
+// NewCamera constructs a camera from geodetic lat/lon (deg), altitude (km) and
+// field of view (deg), looking at Earth's center with Up pointing north.
+//
+// The attitude is then adjusted by three intrinsic rotations, each about the
+// camera's current axes, applied in this order:
+//
+//  1. yaw about Up (positive pans the view toward the left),
+//  2. tilt (pitch) about Right (positive raises the view toward Up),
+//  3. roll about Forward (positive lowers the Right side of the frame).
+func NewCamera(latDeg, lonDeg, altKm, fovDeg, tiltDeg, yawDeg, rollDeg float64) Camera {
+	pos := earth.PositionECEF(latDeg, lonDeg, altKm)
 
-	camRadius := earth.Radius + altKm
-	x := camRadius * math.Cos(lat) * math.Cos(lon)
-	y := camRadius * math.Cos(lat) * math.Sin(lon)
-	z := camRadius * math.Sin(lat)
+	// Basis vectors
+	fwd := pos.Normalize().Scale(-1.0) // look toward Earth center
+	right, up := lookBasis(fwd)
 
-	pos := vectors.Vec3{X: x, Y: y, Z: z}
+	fwd, right, up = orientCamera(fwd, right, up, tiltDeg, yawDeg, rollDeg)
+	return newCameraFromBasis(pos, fwd, right, up, fovDeg)
+}
 
-	// FOV
-	fovRad := fovDeg * math.Pi / 180.0
-	tanHalf := math.Tan(fovRad / 2.0)
+// NewLookAtCamera constructs a camera at geodetic camLat/camLon (deg) and
+// camAlt (km) pointing at the ground coordinate targetLat/targetLon (deg),
+// targetAlt (km). Up is kept as close to north as the view direction allows,
+// so looking at the sub-camera point gives the same framing as NewCamera.
+// rollDeg then rotates the frame about the view direction (see NewCamera).
+func NewLookAtCamera(camLatDeg, camLonDeg, camAltKm, targetLatDeg, targetLonDeg, targetAltKm, fovDeg, rollDeg float64) Camera {
+	pos := earth.PositionECEF(camLatDeg, camLonDeg, camAltKm)
+	target := earth.PositionECEF(targetLatDeg, targetLonDeg, targetAltKm)
 
-	// Basis vectors
-	fwd := pos.Normalize().Scale(-1.0) // look toward Earth center
+	fwd := target.Sub(pos).Normalize()
+	if fwd.Norm() == 0 {
+		fwd = pos.Normalize().Scale(-1.0) // target coincides with camera
+	}
+	right, up := lookBasis(fwd)
+
+	fwd, right, up = orientCamera(fwd, right, up, 0, 0, rollDeg)
+	return newCameraFromBasis(pos, fwd, right, up, fovDeg)
+}
+
+// lookBasis returns Right and Up for a view direction, with Up pointing as
+// far north (+Z) as possible.
+func lookBasis(fwd vectors.Vec3) (vectors.Vec3, vectors.Vec3) {
 	globalUp := vectors.Vec3{X: 0, Y: 0, Z: 1}
 	right := fwd.Cross(globalUp)
 	if right.Norm() < 1e-6 {
@@ -43,10 +103,51 @@ func NewCamera(latDeg, lonDeg, altKm, fovDeg, tiltDeg, yawDeg float64) Camera {
 	}
 	right = right.Normalize()
 	up := right.Cross(fwd).Normalize()
+	return right, up
+}
+
+// NewCameraFromBasis constructs a camera at pos (ECEF, km) looking along fwd,
+// with the given right and up vectors (see attitude.Basis).
+func NewCameraFromBasis(pos, fwd, right, up vectors.Vec3, fovDeg float64) Camera {
+	return newCameraFromBasis(pos, fwd.Normalize(), right.Normalize(), up.Normalize(), fovDeg)
+}
+
+// NewCameraFromMatrix constructs a camera at pos (ECEF, km) whose attitude is
+// given by a rotation matrix mapping camera coordinates to ECEF. The camera
+// frame is right-handed with X = Right, Y = Up and the view direction along -Z
+// (the OpenGL convention), so the matrix columns are Right, Up and -Forward
+// expressed in ECEF.
+func NewCameraFromMatrix(pos vectors.Vec3, m vectors.Mat3, fovDeg float64) Camera {
+	right := m.Col(0).Normalize()
+	up := m.Col(1).Normalize()
+	fwd := m.Col(2).Normalize().Scale(-1)
+	return newCameraFromBasis(pos, fwd, right, up, fovDeg)
+}
+
+// NewCameraFromQuaternion constructs a camera at pos (ECEF, km) whose attitude
+// is the rotation q from camera coordinates to ECEF, using the same camera
+// frame as NewCameraFromMatrix. q does not need to be normalized.
+func NewCameraFromQuaternion(pos vectors.Vec3, q vectors.Quat, fovDeg float64) Camera {
+	return NewCameraFromMatrix(pos, q.Normalize().Mat3(), fovDeg)
+}
+
+// Rotate returns the camera with yaw, tilt and roll (deg) applied relative to
+// its current attitude, in the order documented on NewCamera.
+func (c Camera) Rotate(tiltDeg, yawDeg, rollDeg float64) Camera {
+	c.Forward, c.Right, c.Up = orientCamera(c.Forward, c.Right, c.Up, tiltDeg, yawDeg, rollDeg)
+	return c
+}
+
+// Matrix returns the rotation from camera coordinates to ECEF, with Right, Up
+// and -Forward as its columns. See NewCameraFromMatrix for the camera frame.
+func (c Camera) Matrix() vectors.Mat3 {
+	return vectors.Mat3FromCols(c.Right, c.Up, c.Forward.Scale(-1))
+}
+
+func newCameraFromBasis(pos, fwd, right, up vectors.Vec3, fovDeg float64) Camera {
+	fovRad := fovDeg * math.Pi / 180.0
+	tanHalf := math.Tan(fovRad / 2.0)
 
-	if tiltDeg != 0 {
-		fwd, right, up = tiltCamera(fwd, right, up, tiltDeg)
-	}
 	return Camera{
 		FOVDeg:     fovDeg,
 		TanHalfFOV: tanHalf,
@@ -57,6 +158,21 @@ func NewCamera(latDeg, lonDeg, altKm, fovDeg, tiltDeg, yawDeg float64) Camera {
 	}
 }
 
+// orientCamera applies yaw, tilt and roll (deg) to the basis, in that order.
+// Zero angles are skipped so the basis is left bit-for-bit unchanged.
+func orientCamera(fwd, right, up vectors.Vec3, tiltDeg, yawDeg, rollDeg float64) (vectors.Vec3, vectors.Vec3, vectors.Vec3) {
+	if yawDeg != 0 {
+		fwd, right, up = yawCamera(fwd, right, up, yawDeg)
+	}
+	if tiltDeg != 0 {
+		fwd, right, up = tiltCamera(fwd, right, up, tiltDeg)
+	}
+	if rollDeg != 0 {
+		fwd, right, up = rollCamera(fwd, right, up, rollDeg)
+	}
+	return fwd, right, up
+}
+
 // rotateVec applies Rodrigues’ rotation formula: rotate v around axis by (cosT, sinT).
 func rotateVec(v, axis vectors.Vec3, cosT, sinT float64) vectors.Vec3 {
 	// v*cos + (axis x v)*sin + axis*(axis·v)*(1-cos)
@@ -86,18 +202,39 @@ func yawCamera(fwd, right, up vectors.Vec3, yawDeg float64) (vectors.Vec3, vecto
 	return fwdNew, rightNew, up
 }
 
-// ComputeRay returns the normalized viewing direction for pixel (i,j)
+// rollCamera rotates right/up around the Forward axis by rollDeg.
+func rollCamera(fwd, right, up vectors.Vec3, rollDeg float64) (vectors.Vec3, vectors.Vec3, vectors.Vec3) {
+	theta := rollDeg * math.Pi / 180.0
+	c, s := math.Cos(theta), math.Sin(theta)
+
+	rightNew := rotateVec(right, fwd, c, s).Normalize()
+	upNew := rotateVec(up, fwd, c, s).Normalize()
+	return fwd, rightNew, upNew
+}
+
+// Ray returns the viewing direction for pixel (i,j) using the camera's
+// Projection. ok is false if the projection does not cover the pixel.
+func (c Camera) Ray(i, j float64, width, height int) (vectors.Vec3, bool) {
+	return c.projection().Ray(c, i, j, width, height)
+}
+
+func (c Camera) projection() Projection {
+	if c.Projection == nil {
+		return Pinhole{}
+	}
+	return c.Projection
+}
+
+// ComputeRay returns the normalized pinhole viewing direction for pixel (i,j)
 // given the image dimensions (width,height). i,j can be fractional (for supersampling).
+// Pixels are square: the axis selected by FOVAxis spans FOVDeg and the other
+// is scaled by the aspect ratio.
 func (c Camera) ComputeRay(i, j float64, width, height int) vectors.Vec3 {
-	w := float64(width)
-	h := float64(height)
+	xNDC, yNDC := pixelToNDC(i, j, width, height)
 
-	// NDC in [-1, +1] (centered), flip Y to make +up in screen space.
-	xNDC := (i - (w-1)/2.0) / ((w - 1) / 2.0)
-	yNDC := -((j - (h-1)/2.0) / ((h - 1) / 2.0))
-
-	xPlane := xNDC * c.TanHalfFOV
-	yPlane := yNDC * c.TanHalfFOV
+	tanX, tanY := c.TanHalfFOVXY(width, height)
+	xPlane := xNDC * tanX
+	yPlane := yNDC * tanY
 	zPlane := 1.0
 
 	dir := c.Right.Scale(xPlane).
@@ -106,3 +243,28 @@ func (c Camera) ComputeRay(i, j float64, width, height int) vectors.Vec3 {
 
 	return dir.Normalize()
 }
+
+// TanHalfFOVXY returns the tangents of the horizontal and vertical half-angles
+// of view of the pinhole projection for an image of the given size.
+func (c Camera) TanHalfFOVXY(width, height int) (float64, float64) {
+	sx, sy := c.FOVAxis.scale(width, height)
+	return c.TanHalfFOV * sx, c.TanHalfFOV * sy
+}
+
+// scale returns the extent of the image along x and y relative to the FOV
+// axis, which has extent 1.
+func (a FOVAxis) scale(width, height int) (float64, float64) {
+	// Spans between the outermost pixel centers, matching pixelToNDC.
+	w := math.Max(float64(width-1), 1)
+	h := math.Max(float64(height-1), 1)
+
+	switch a {
+	case FOVVertical:
+		return w / h, 1
+	case FOVDiagonal:
+		d := math.Hypot(w, h)
+		return w / d, h / d
+	default:
+		return 1, h / w
+	}
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+func vecNear(a, b vectors.Vec3, eps float64) bool {
+	return vectors.Distance(a, b) < eps
+}
+
+func TestCameraAttitude(t *testing.T) {
+	base := NewCamera(0, 0, 1000, 60, 0, 0, 0)
+
+	// At (0,0) looking down: Forward = -X, Right = +Y (east), Up = +Z (north).
+	if !vecNear(base.Forward, vectors.Vec3{X: -1}, 1e-9) ||
+		!vecNear(base.Up, vectors.Vec3{Z: 1}, 1e-9) {
+		t.Fatalf("unexpected nadir basis: %+v", base)
+	}
+
+	cases := []struct {
+		name             string
+		tilt, yaw, roll  float64
+		wantFwd, wantUp  vectors.Vec3
+		wantRightChanged bool
+	}{
+		{"yaw", 0, 90, 0, base.Right.Scale(-1), base.Up, true},
+		{"tilt", 90, 0, 0, base.Up, base.Forward.Scale(-1), false},
+		{"roll", 0, 0, 90, base.Forward, base.Right, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cam := NewCamera(0, 0, 1000, 60, c.tilt, c.yaw, c.roll)
+			if !vecNear(cam.Forward, c.wantFwd, 1e-9) {
+				t.Errorf("Forward = %+v, want %+v", cam.Forward, c.wantFwd)
+			}
+			if !vecNear(cam.Up, c.wantUp, 1e-9) {
+				t.Errorf("Up = %+v, want %+v", cam.Up, c.wantUp)
+			}
+			if changed := !vecNear(cam.Right, base.Right, 1e-9); changed != c.wantRightChanged {
+				t.Errorf("Right changed = %v, want %v", changed, c.wantRightChanged)
+			}
+		})
+	}
+}
+
+func TestCameraFromQuaternion(t *testing.T) {
+	ref := NewCamera(35, -120, 700, 45, 20, 30, -15)
+
+	q := vectors.QuatFromMat3(ref.Matrix())
+	cam := NewCameraFromQuaternion(ref.Position, q, ref.FOVDeg)
+
+	for _, pair := range [][2]vectors.Vec3{
+		{cam.Forward, ref.Forward},
+		{cam.Right, ref.Right},
+		{cam.Up, ref.Up},
+	} {
+		if !vecNear(pair[0], pair[1], 1e-9) {
+			t.Fatalf("basis mismatch: got %+v, want %+v", pair[0], pair[1])
+		}
+	}
+	if math.Abs(cam.TanHalfFOV-ref.TanHalfFOV) > 1e-12 {
+		t.Fatalf("TanHalfFOV = %v, want %v", cam.TanHalfFOV, ref.TanHalfFOV)
+	}
+}
+
+func TestLookAtCamera(t *testing.T) {
+	// Looking at the sub-camera point matches the default nadir camera.
+	nadir := NewCamera(40, 10, 800, 50, 0, 0, 0)
+	cam := NewLookAtCamera(40, 10, 800, 40, 10, 0, 50, 0)
+	if !vecNear(cam.Forward, nadir.Forward, 1e-9) || !vecNear(cam.Up, nadir.Up, 1e-9) {
+		t.Fatalf("nadir look-at mismatch: got %+v, want %+v", cam, nadir)
+	}
+
+	// Oblique target: the target must sit on the optical axis.
+	cam = NewLookAtCamera(40, 10, 800, 45, 15, 0, 50, 30)
+	target := earth.PositionECEF(45, 15, 0)
+	if !vecNear(cam.Forward, target.Sub(cam.Position).Normalize(), 1e-9) {
+		t.Fatalf("target is off-axis: Forward = %+v", cam.Forward)
+	}
+
+	// Pole fallback keeps an orthonormal basis.
+	cam = NewLookAtCamera(90, 0, 800, 90, 0, 0, 50, 0)
+	if math.Abs(cam.Right.Dot(cam.Forward)) > 1e-9 || math.Abs(cam.Right.Norm()-1) > 1e-9 {
+		t.Fatalf("degenerate basis at pole: %+v", cam)
+	}
+}
+
+func TestTanHalfFOVXY(t *testing.T) {
+	cam := NewCamera(0, 0, 1000, 90, 0, 0, 0) // tan(45°) = 1
+
+	cases := []struct {
+		axis       FOVAxis
+		tanX, tanY float64
+	}{
+		{FOVHorizontal, 1, 0.5},
+		{FOVVertical, 2, 1},
+		{FOVDiagonal, 2 / math.Sqrt(5), 1 / math.Sqrt(5)},
+	}
+	for _, c := range cases {
+		cam.FOVAxis = c.axis
+		tanX, tanY := cam.TanHalfFOVXY(201, 101)
+		if math.Abs(tanX-c.tanX) > 1e-12 || math.Abs(tanY-c.tanY) > 1e-12 {
+			t.Errorf("%v: got (%v, %v), want (%v, %v)", c.axis, tanX, tanY, c.tanX, c.tanY)
+		}
+	}
+}
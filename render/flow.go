@@ -0,0 +1,126 @@
+package render
+
+import (
+	"math"
+
+	"github.com/echoflaresat/spacecam/earth"
+)
+
+// Motion between two global textures is estimated by block matching on a
+// coarse latitude/longitude grid of their brightness.
+const (
+	flowCellDeg = 1.0 // grid spacing in degrees
+	flowSearch  = 4   // largest displacement tried, in cells each way
+	flowBlock   = 2   // half-width in cells of the compared blocks
+
+	// flowTolerance is the difference in the sum of absolute differences
+	// of a block below which two matches count as equally good: half a
+	// level of 8-bit brightness per cell.
+	flowTolerance = 0.5 / 255 * (2*flowBlock + 1) * (2*flowBlock + 1)
+)
+
+// flowField holds a displacement in degrees of latitude and longitude for
+// each grid cell, from the first texture to the second.
+type flowField struct {
+	w, h       int
+	dLat, dLon []float64
+}
+
+// estimateFlow finds, for each cell of a, the displacement within
+// ±flowSearch cells that best matches the surrounding block in b, and
+// smooths the result. Longitudes wrap; latitudes are clamped at the poles.
+func estimateFlow(a, b TextureSampler) *flowField {
+	w, h := int(360/flowCellDeg), int(180/flowCellDeg)
+	ga, gb := brightnessGrid(a, w, h), brightnessGrid(b, w, h)
+	at := func(g []float64, x, y int) float64 {
+		x = ((x % w) + w) % w
+		y = min(max(y, 0), h-1)
+		return g[y*w+x]
+	}
+
+	dx := make([]float64, w*h)
+	dy := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			best, bestX, bestY := math.Inf(1), 0, 0
+			for sy := -flowSearch; sy <= flowSearch; sy++ {
+				for sx := -flowSearch; sx <= flowSearch; sx++ {
+					var sad float64
+					for j := -flowBlock; j <= flowBlock; j++ {
+						for i := -flowBlock; i <= flowBlock; i++ {
+							sad += math.Abs(at(ga, x+i, y+j) - at(gb, x+i+sx, y+j+sy))
+						}
+					}
+					// Prefer the smaller displacement on near ties, so
+					// that featureless areas, like clear sky, stay put.
+					if sad < best-flowTolerance || sad <= best+flowTolerance && sx*sx+sy*sy < bestX*bestX+bestY*bestY {
+						best, bestX, bestY = sad, sx, sy
+					}
+				}
+			}
+			dx[y*w+x], dy[y*w+x] = float64(bestX), float64(bestY)
+		}
+	}
+
+	// Average over 3×3 cells to calm the noise of the matching. Grid rows
+	// run from north to south, so a displacement down is toward lower
+	// latitude.
+	f := &flowField{w: w, h: h, dLat: make([]float64, w*h), dLon: make([]float64, w*h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sx, sy float64
+			for j := -1; j <= 1; j++ {
+				for i := -1; i <= 1; i++ {
+					sx += at(dx, x+i, y+j)
+					sy += at(dy, x+i, y+j)
+				}
+			}
+			f.dLon[y*w+x] = sx / 9 * flowCellDeg
+			f.dLat[y*w+x] = -sy / 9 * flowCellDeg
+		}
+	}
+	return f
+}
+
+// brightnessGrid samples the mean of R, G and B of s at the center of each
+// cell of a w×h grid, row 0 at the north pole.
+func brightnessGrid(s TextureSampler, w, h int) []float64 {
+	footprint := 2 * math.Pi * earth.Radius / float64(w) // a cell at the equator
+	g := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		lat := 90 - (float64(y)+0.5)*180/float64(h)
+		for x := 0; x < w; x++ {
+			lon := -180 + (float64(x)+0.5)*360/float64(w)
+			c := sampleSurface(s, unitVector(lat, lon), footprint)
+			g[y*w+x] = (c.R + c.G + c.B) / 3
+		}
+	}
+	return g
+}
+
+// at returns the displacement at a latitude and longitude in degrees,
+// interpolated bilinearly between the cell centers.
+func (f *flowField) at(lat, lon float64) (dLat, dLon float64) {
+	u := (lon+180)/360*float64(f.w) - 0.5
+	v := (90-lat)/180*float64(f.h) - 0.5
+	x0, y0 := math.Floor(u), math.Floor(v)
+	fx, fy := u-x0, v-y0
+
+	for j := 0; j < 2; j++ {
+		y := min(max(int(y0)+j, 0), f.h-1)
+		wy := fy
+		if j == 0 {
+			wy = 1 - fy
+		}
+		for i := 0; i < 2; i++ {
+			x := ((int(x0)+i)%f.w + f.w) % f.w
+			wx := fx
+			if i == 0 {
+				wx = 1 - fx
+			}
+			dLat += wx * wy * f.dLat[y*f.w+x]
+			dLon += wx * wy * f.dLon[y*f.w+x]
+		}
+	}
+	return dLat, dLon
+}
@@ -0,0 +1,180 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/colors"
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/tiffenc"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// minMipSize is the edge length below which no further pyramid levels are
+// built.
+const minMipSize = 256
+
+// mipPath returns the cache file of pyramid level n of the texture at path,
+// next to it: world.200408.tif → world.200408.mip2.tif.
+func mipPath(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.mip%d.tif", strings.TrimSuffix(path, ext), n)
+}
+
+// loadMipmaps opens the image pyramid of t, whose source file is path,
+// building the levels that are missing or older than the level they are made
+// from. Each level halves the previous one and is stored as a tiled TIFF, so
+// that it is read lazily like the source, through cache if it is not nil.
+func (t *Texture) loadMipmaps(path string, cache *TileCache) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	src, srcTime := *t, info.ModTime()
+	for n := 1; max(src.Width, src.Height) > minMipSize; n++ {
+		w, h := (src.Width+1)/2, (src.Height+1)/2
+		level, levelTime, err := loadMipLevel(mipPath(path, n), w, h, srcTime, cache)
+		if err != nil {
+			if err := buildMipLevel(src.img, mipPath(path, n)); err != nil {
+				return fmt.Errorf("building mipmap level %d of %s: %w", n, path, err)
+			}
+			if level, levelTime, err = loadMipLevel(mipPath(path, n), w, h, time.Time{}, cache); err != nil {
+				return err
+			}
+		}
+		level.Filter = t.Filter
+		t.mips = append(t.mips, level)
+		src, srcTime = level, levelTime
+	}
+	return nil
+}
+
+// loadMipLevel opens a cached pyramid level and returns its modification
+// time. It fails if the file is missing, older than notBefore or not w×h.
+func loadMipLevel(path string, w, h int, notBefore time.Time, cache *TileCache) (Texture, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Texture{}, time.Time{}, err
+	}
+	if info.ModTime().Before(notBefore) {
+		return Texture{}, time.Time{}, fmt.Errorf("%s is out of date", path)
+	}
+	level, err := LoadTextureCached(path, cache)
+	if err != nil {
+		return Texture{}, time.Time{}, err
+	}
+	if level.Width != w || level.Height != h {
+		level.Close()
+		return Texture{}, time.Time{}, fmt.Errorf("%s is %dx%d, want %dx%d", path, level.Width, level.Height, w, h)
+	}
+	return level, info.ModTime(), nil
+}
+
+// buildMipLevel writes src downsampled by two, averaging 2×2 blocks, as a
+// tiled TIFF at path. It goes tile by tile, so src is read with the locality
+// of a tiled TIFF and never has to fit in memory. The file is written under a
+// temporary name and renamed into place, so that processes sharing the
+// textures never see a partial level.
+func buildMipLevel(src image.Image, path string) error {
+	b := src.Bounds()
+	w, h := (b.Dx()+1)/2, (b.Dy()+1)/2
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	tw, err := tiffenc.NewTiled(f, w, h, tiffenc.DefaultTileSize)
+	if err != nil {
+		return err
+	}
+	across, down := tw.Tiles()
+	tile := image.NewNRGBA(image.Rect(0, 0, tiffenc.DefaultTileSize, tiffenc.DefaultTileSize))
+	for row := 0; row < down; row++ {
+		for col := 0; col < across; col++ {
+			r := tw.TileBounds(col, row)
+			tile.Rect = r
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					tile.SetNRGBA(x, y, averageBlock(src, b.Min.X+2*x, b.Min.Y+2*y))
+				}
+			}
+			if err := tw.WriteTile(col, row, tile); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// averageBlock returns the mean of the 2×2 block of src at (x, y). At the
+// right and bottom edges of an odd-sized image the block is clamped.
+func averageBlock(src image.Image, x, y int) color.NRGBA {
+	b := src.Bounds()
+	var sr, sg, sb uint32
+	for _, p := range [4]image.Point{{x, y}, {x + 1, y}, {x, y + 1}, {x + 1, y + 1}} {
+		r, g, bl, _ := src.At(min(p.X, b.Max.X-1), min(p.Y, b.Max.Y-1)).RGBA()
+		sr, sg, sb = sr+r>>8, sg+g>>8, sb+bl>>8
+	}
+	return color.NRGBA{uint8((sr + 2) / 4), uint8((sg + 2) / 4), uint8((sb + 2) / 4), 255}
+}
+
+// SampleFootprint samples t at P for a pixel that covers footprint
+// kilometers of ground. Without mipmaps, or when a texel is larger than the
+// footprint, it is the same as Sample. Otherwise it blends the two pyramid
+// levels whose texels are nearest the footprint in size, which averages away
+// the detail a single sample would alias.
+func (t Texture) SampleFootprint(P vectors.Vec3, footprint float64) colors.Color4 {
+	if len(t.mips) == 0 || footprint <= 0 {
+		return t.Sample(P)
+	}
+	texel := 2 * math.Pi * earth.Radius / float64(t.Width)
+	lod := math.Log2(footprint / texel)
+	if lod <= 0 {
+		return t.Sample(P)
+	}
+	lod = min(lod, float64(len(t.mips)))
+
+	n := int(lod)
+	c := t.mipLevel(n).Sample(P)
+	if f := lod - float64(n); f > 0 {
+		c = c.Mix(t.mipLevel(n+1).Sample(P), f)
+	}
+	return c
+}
+
+// mipLevel returns pyramid level n, where level 0 is t itself.
+func (t Texture) mipLevel(n int) Texture {
+	if n == 0 {
+		return t
+	}
+	return t.mips[n-1]
+}
+
+// closeMipmaps closes the files of the pyramid levels.
+func (t Texture) closeMipmaps() error {
+	var errs []error
+	for _, m := range t.mips {
+		errs = append(errs, m.Close())
+	}
+	return errors.Join(errs...)
+}
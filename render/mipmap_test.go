@@ -0,0 +1,111 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeChecker writes a w×h texture of alternating black and white texels.
+func writeChecker(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(255 * ((x + y) % 2))
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMipmaps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checker.png")
+	writeChecker(t, path, 1030, 515) // odd sizes further down
+
+	tex, err := LoadTexture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Close()
+	if err := tex.loadMipmaps(path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]int{{515, 258}, {258, 129}, {129, 65}}
+	if len(tex.mips) != len(want) {
+		t.Fatalf("%d levels, want %d", len(tex.mips), len(want))
+	}
+	for i, m := range tex.mips {
+		if m.Width != want[i][0] || m.Height != want[i][1] {
+			t.Errorf("level %d is %dx%d, want %dx%d", i+1, m.Width, m.Height, want[i][0], want[i][1])
+		}
+	}
+	// Every 2×2 block of the checkerboard averages to gray.
+	if c := tex.mips[0].texel(10, 10); math.Abs(c.R-0.5) > 0.01 {
+		t.Errorf("level 1 texel = %.3f, want 0.5", c.R)
+	}
+
+	P := texturePoint(tex, 100.5, 100.5)
+	if c := tex.SampleFootprint(P, 0); c != tex.Sample(P) {
+		t.Errorf("SampleFootprint without a footprint = %v, want Sample = %v", c, tex.Sample(P))
+	}
+	if c := tex.SampleFootprint(P, 1000); math.Abs(c.R-0.5) > 0.01 {
+		t.Errorf("SampleFootprint over many texels = %.3f, want 0.5", c.R)
+	}
+}
+
+func TestMipmapsCached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checker.png")
+	writeChecker(t, path, 600, 300)
+
+	load := func() {
+		tex, err := LoadTexture(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tex.loadMipmaps(path, nil); err != nil {
+			t.Fatal(err)
+		}
+		tex.Close()
+	}
+	load()
+	info, err := os.Stat(mipPath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	load()
+	again, err := os.Stat(mipPath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.ModTime().Equal(info.ModTime()) {
+		t.Error("level 1 was rebuilt although it was up to date")
+	}
+
+	// A newer source invalidates the cache.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	load()
+	if again, err = os.Stat(mipPath(path, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if again.ModTime().Equal(info.ModTime()) {
+		t.Error("level 1 was not rebuilt after the source changed")
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/colors"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// TimeVaryingSampler is a TextureSampler whose image changes with the date,
+// such as MonthlyTexture. Render samples it as of Options.Time.
+type TimeVaryingSampler interface {
+	TextureSampler
+	// AtTime returns the sampler for the moment t.
+	AtTime(t time.Time) TextureSampler
+}
+
+// IsMonthlyPattern reports whether path is a pattern for monthly textures,
+// with a verb like %02d for the month number.
+func IsMonthlyPattern(path string) bool {
+	return strings.Contains(path, "%")
+}
+
+// MonthlyTexture is a texture with one image per month, like NASA's monthly
+// Blue Marble mosaics. Each image is taken to show the middle of its month;
+// in between, the two nearest months are blended.
+type MonthlyTexture struct {
+	Months [12]Texture // January first
+}
+
+// LoadMonthlyTexture loads the twelve images named by pattern for the month
+// numbers 1 to 12, e.g. world.2004%02d.tif, with load.
+func LoadMonthlyTexture(pattern string, load func(path string) (Texture, error)) (*MonthlyTexture, error) {
+	m := &MonthlyTexture{}
+	for i := range m.Months {
+		path := fmt.Sprintf(pattern, i+1)
+		tex, err := load(path)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("month %d of %s: %w", i+1, pattern, err)
+		}
+		m.Months[i] = tex
+	}
+	return m, nil
+}
+
+// AtTime returns the blend of the two months nearest t.
+func (m *MonthlyTexture) AtTime(t time.Time) TextureSampler {
+	a, b, f := monthBlend(t)
+	if f == 0 {
+		return m.Months[a]
+	}
+	return blendSampler{m.Months[a], m.Months[b], f}
+}
+
+// Sample samples January; Render samples AtTime(Options.Time) instead.
+func (m *MonthlyTexture) Sample(P vectors.Vec3) colors.Color4 {
+	return m.Months[0].Sample(P)
+}
+
+// SampleLatLon samples January, like Sample.
+func (m *MonthlyTexture) SampleLatLon(lat, lon float64) colors.Color4 {
+	return m.Months[0].SampleLatLon(lat, lon)
+}
+
+// Close closes the images of all months.
+func (m *MonthlyTexture) Close() error {
+	var errs []error
+	for _, tex := range m.Months {
+		errs = append(errs, tex.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// monthBlend returns the months (0 for January) whose middles are just
+// before and after t, and how far t is from the first to the second.
+func monthBlend(t time.Time) (a, b int, f float64) {
+	t = t.UTC()
+	year, month := t.Year(), t.Month()
+	if t.Before(monthMiddle(year, month)) {
+		month--
+	}
+	from, to := monthMiddle(year, month), monthMiddle(year, month+1)
+	f = float64(t.Sub(from)) / float64(to.Sub(from))
+	// time.Date normalizes month 0 and 13; so do these.
+	a = (int(month) + 11) % 12
+	b = (a + 1) % 12
+	return a, b, f
+}
+
+// monthMiddle returns the middle of the month, which may be out of the
+// range 1 to 12 like in time.Date.
+func monthMiddle(year int, month time.Month) time.Time {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Add(end.Sub(start) / 2)
+}
+
+// blendSampler mixes two samplers, a weighted 1-f and b weighted f.
+type blendSampler struct {
+	a, b TextureSampler
+	f    float64
+}
+
+func (s blendSampler) Sample(P vectors.Vec3) colors.Color4 {
+	return s.a.Sample(P).Mix(s.b.Sample(P), s.f)
+}
+
+func (s blendSampler) SampleLatLon(lat, lon float64) colors.Color4 {
+	return s.a.SampleLatLon(lat, lon).Mix(s.b.SampleLatLon(lat, lon), s.f)
+}
+
+func (s blendSampler) SampleFootprint(P vectors.Vec3, footprint float64) colors.Color4 {
+	return sampleSurface(s.a, P, footprint).Mix(sampleSurface(s.b, P, footprint), s.f)
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+)
+
+func TestMonthBlend(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		a, b int
+		f    float64
+	}{
+		{time.Date(2024, 8, 16, 12, 0, 0, 0, time.UTC), 7, 8, 0},         // middle of August
+		{time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), 7, 8, 15.5 / 30.5}, // August 16 12:00 to September 16
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 11, 0, 0.5},        // across the new year
+		{time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), 11, 0, 14.5 / 31},
+	}
+	for _, tt := range tests {
+		a, b, f := monthBlend(tt.t)
+		if a != tt.a || b != tt.b || math.Abs(f-tt.f) > 1e-9 {
+			t.Errorf("monthBlend(%v) = %d, %d, %.4f, want %d, %d, %.4f", tt.t, a, b, f, tt.a, tt.b, tt.f)
+		}
+	}
+}
+
+// monthTexture is a 2×1 texture with red m/12.
+func monthTexture(m int) Texture {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	for x := 0; x < 2; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{uint8(m * 255 / 12), 0, 0, 255})
+	}
+	return NewImageTexture(img, FilterNearest)
+}
+
+func TestMonthlyTextureAtTime(t *testing.T) {
+	months, err := LoadMonthlyTexture("m%d", func(path string) (Texture, error) {
+		var m int
+		_, err := fmt.Sscanf(path, "m%d", &m)
+		return monthTexture(m), err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Halfway between the middles of March and April.
+	mid := monthMiddle(2024, 3).Add(monthMiddle(2024, 4).Sub(monthMiddle(2024, 3)) / 2)
+	c := months.AtTime(mid).SampleLatLon(0, 0)
+	want := (float64(3*255/12) + float64(4*255/12)) / 2 / 255
+	if math.Abs(c.R-want) > 1e-9 {
+		t.Errorf("R = %.4f, want %.4f", c.R, want)
+	}
+}
+
+func TestRenderMonthlyNeedsTime(t *testing.T) {
+	r := testRenderer(1)
+	months := &MonthlyTexture{}
+	for i := range months.Months {
+		months.Months[i] = monthTexture(i + 1)
+	}
+	r.tex.Day = months
+
+	camera := NewCamera(0, 0, 8000, 30, 0, 0, 0)
+	opts := Options{Width: 8, Height: 8}
+	if _, err := r.Render(context.Background(), camera, camera.Position.Normalize(), opts); err == nil {
+		t.Error("expected an error rendering monthly textures without a time")
+	}
+	opts.Time = time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC)
+	if _, err := r.Render(context.Background(), camera, earth.SunDirectionECEF(opts.Time), opts); err != nil {
+		t.Error(err)
+	}
+}
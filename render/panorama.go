@@ -0,0 +1,315 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"time"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// CubeFace identifies one face of a cube map, relative to the camera that
+// the cube is centered on.
+type CubeFace int
+
+const (
+	CubeFront CubeFace = iota
+	CubeRight
+	CubeBack
+	CubeLeft
+	CubeUp
+	CubeDown
+)
+
+// CubeFaces lists all faces in render order.
+var CubeFaces = [6]CubeFace{CubeFront, CubeRight, CubeBack, CubeLeft, CubeUp, CubeDown}
+
+func (f CubeFace) String() string {
+	switch f {
+	case CubeFront:
+		return "front"
+	case CubeRight:
+		return "right"
+	case CubeBack:
+		return "back"
+	case CubeLeft:
+		return "left"
+	case CubeUp:
+		return "up"
+	case CubeDown:
+		return "down"
+	}
+	return fmt.Sprintf("CubeFace(%d)", int(f))
+}
+
+// basis returns the face's forward, right and up axes in camera space
+// (x right, y up, z forward). Faces are oriented so that they fold into the
+// usual horizontal cross: Left, Front, Right, Back in a row, with Up above
+// and Down below Front.
+func (f CubeFace) basis() (fwd, right, up vectors.Vec3) {
+	X := vectors.Vec3{X: 1}
+	Y := vectors.Vec3{Y: 1}
+	Z := vectors.Vec3{Z: 1}
+	switch f {
+	case CubeRight:
+		return X, Z.Scale(-1), Y
+	case CubeBack:
+		return Z.Scale(-1), X.Scale(-1), Y
+	case CubeLeft:
+		return X.Scale(-1), Z, Y
+	case CubeUp:
+		return Y, X, Z.Scale(-1)
+	case CubeDown:
+		return Y.Scale(-1), X, Z
+	default:
+		return Z, X, Y
+	}
+}
+
+// CubeFaceCamera returns the pinhole camera that renders one face of a cube
+// map of faceSize×faceSize pixels centered on c.
+func CubeFaceCamera(c Camera, face CubeFace, faceSize int) Camera {
+	fwd, right, up := face.basis()
+
+	// ComputeRay puts the outermost pixel centers on the FOV edge. Widen the
+	// FOV so that the pixel edges, not centers, meet at the cube edges and
+	// neighbouring faces don't duplicate a row of pixels.
+	n := float64(faceSize)
+	fovDeg := 2 * math.Atan((n-1)/n) * 180.0 / math.Pi
+
+	return newCameraFromBasis(
+		c.Position,
+		c.toWorld(fwd.X, fwd.Y, fwd.Z),
+		c.toWorld(right.X, right.Y, right.Z),
+		c.toWorld(up.X, up.Y, up.Z),
+		fovDeg,
+	)
+}
+
+// RenderCubeMap renders the six faceSize×faceSize faces of a cube map from
+// the camera position, oriented relative to the camera's attitude. The FOV
+// and projection of camera are ignored. Faces are indexed by CubeFace.
+// renderTime is passed on as Options.Time. progress, if not nil, follows all
+// six faces as if they were one frame.
+func (r *Renderer) RenderCubeMap(
+	ctx context.Context,
+	camera Camera,
+	sunDir vectors.Vec3,
+	renderTime time.Time,
+	faceSize int,
+	supersampling int,
+	progress ProgressObserver,
+) ([6]*image.NRGBA, error) {
+	var faces [6]*image.NRGBA
+	opts := Options{Width: faceSize, Height: faceSize, Supersampling: supersampling, Time: renderTime}
+	start := time.Now()
+	for i, face := range CubeFaces {
+		if progress != nil {
+			opts.Progress = ProgressFunc(func(p Progress) {
+				before := int64(i) * p.Total
+				total := int64(len(CubeFaces)) * p.Total
+				done := before + p.Done
+				elapsed := time.Since(start)
+				eta := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+				progress.RenderProgress(Progress{Done: done, Total: total, Elapsed: elapsed, ETA: eta})
+			})
+		}
+		img, err := r.Render(ctx, CubeFaceCamera(camera, face, faceSize), sunDir, opts)
+		if err != nil {
+			return faces, fmt.Errorf("cube face %v: %w", face, err)
+		}
+		faces[face] = img
+	}
+	return faces, nil
+}
+
+// CubeCross lays out cube faces as a horizontal cross (4×3 faces):
+//
+//	      Up
+//	Left Front Right Back
+//	     Down
+func CubeCross(faces [6]*image.NRGBA) *image.NRGBA {
+	n := faces[CubeFront].Bounds().Dx()
+	out := image.NewNRGBA(image.Rect(0, 0, 4*n, 3*n))
+
+	cells := map[CubeFace]image.Point{
+		CubeUp:    {1, 0},
+		CubeLeft:  {0, 1},
+		CubeFront: {1, 1},
+		CubeRight: {2, 1},
+		CubeBack:  {3, 1},
+		CubeDown:  {1, 2},
+	}
+	for face, cell := range cells {
+		min := cell.Mul(n)
+		draw.Draw(out, image.Rectangle{Min: min, Max: min.Add(image.Point{n, n})}, faces[face], image.Point{}, draw.Src)
+	}
+	return out
+}
+
+// StitchEquirectangular resamples cube faces into a width×height
+// equirectangular panorama, using the same layout as the Equirectangular
+// projection (camera Forward in the center, Up at the top).
+func StitchEquirectangular(faces [6]*image.NRGBA, width, height int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	n := faces[CubeFront].Bounds().Dx()
+
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			d := equirectangularDir(float64(i), float64(j), width, height)
+			face, u, v := cubeFaceCoords(d)
+
+			// u,v in [-1,1] span the face edges; convert to pixel centers.
+			px := (u+1)/2*float64(n) - 0.5
+			py := (1-v)/2*float64(n) - 0.5
+			out.SetNRGBA(i, j, sampleBilinearNRGBA(faces[face], px, py))
+		}
+	}
+	return out
+}
+
+// cubeFaceCoords returns the face that camera-space direction d hits and the
+// face-local coordinates (u right, v up) in [-1, 1].
+func cubeFaceCoords(d vectors.Vec3) (CubeFace, float64, float64) {
+	best := CubeFront
+	bestDot := math.Inf(-1)
+	for _, face := range CubeFaces {
+		fwd, _, _ := face.basis()
+		if dot := d.Dot(fwd); dot > bestDot {
+			best, bestDot = face, dot
+		}
+	}
+	_, right, up := best.basis()
+	return best, d.Dot(right) / bestDot, d.Dot(up) / bestDot
+}
+
+// sampleBilinearNRGBA samples img at fractional pixel coordinates, clamping
+// at the image border.
+func sampleBilinearNRGBA(img *image.NRGBA, x, y float64) color.NRGBA {
+	b := img.Bounds()
+	maxX := float64(b.Dx() - 1)
+	maxY := float64(b.Dy() - 1)
+	x = Clip(x, 0, maxX)
+	y = Clip(y, 0, maxY)
+
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, b.Dx()-1), min(y0+1, b.Dy()-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	c00 := img.NRGBAAt(b.Min.X+x0, b.Min.Y+y0)
+	c10 := img.NRGBAAt(b.Min.X+x1, b.Min.Y+y0)
+	c01 := img.NRGBAAt(b.Min.X+x0, b.Min.Y+y1)
+	c11 := img.NRGBAAt(b.Min.X+x1, b.Min.Y+y1)
+
+	mix := func(a, b, c, d uint8) uint8 {
+		top := float64(a)*(1-fx) + float64(b)*fx
+		bottom := float64(c)*(1-fx) + float64(d)*fx
+		return uint8(math.Round(top*(1-fy) + bottom*fy))
+	}
+	return color.NRGBA{
+		R: mix(c00.R, c10.R, c01.R, c11.R),
+		G: mix(c00.G, c10.G, c01.G, c11.G),
+		B: mix(c00.B, c10.B, c01.B, c11.B),
+		A: mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// panoramaXMP returns the GPano XMP packet that marks an image as a full
+// 360°×180° equirectangular panorama for spherical photo viewers.
+func panoramaXMP(width, height int) []byte {
+	return []byte(fmt.Sprintf(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:GPano="http://ns.google.com/photos/1.0/panorama/"
+   GPano:ProjectionType="equirectangular"
+   GPano:UsePanoramaViewer="True"
+   GPano:FullPanoWidthPixels="%[1]d"
+   GPano:FullPanoHeightPixels="%[2]d"
+   GPano:CroppedAreaImageWidthPixels="%[1]d"
+   GPano:CroppedAreaImageHeightPixels="%[2]d"
+   GPano:CroppedAreaLeftPixels="0"
+   GPano:CroppedAreaTopPixels="0"/>
+ </rdf:RDF>
+</x:xmpmeta>`, width, height))
+}
+
+// EncodePanoramaPNG writes img as a PNG carrying equirectangular panorama
+// metadata (GPano XMP in an iTXt chunk).
+func EncodePanoramaPNG(w io.Writer, img image.Image) error {
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// iTXt: keyword, NUL, compression flag, compression method,
+	// language tag, NUL, translated keyword, NUL, text.
+	var chunk bytes.Buffer
+	chunk.WriteString("XML:com.adobe.xmp")
+	chunk.Write([]byte{0, 0, 0, 0, 0})
+	chunk.Write(panoramaXMP(img.Bounds().Dx(), img.Bounds().Dy()))
+
+	// Insert right after the 8-byte signature and the 25-byte IHDR chunk.
+	const ihdrEnd = 8 + 25
+	data := buf.Bytes()
+	if _, err := w.Write(data[:ihdrEnd]); err != nil {
+		return err
+	}
+	if err := writePNGChunk(w, "iTXt", chunk.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(data[ihdrEnd:])
+	return err
+}
+
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodePanoramaJPEG writes img as a JPEG carrying equirectangular panorama
+// metadata (GPano XMP in an APP1 segment).
+func EncodePanoramaJPEG(w io.Writer, img image.Image, quality int) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+
+	payload := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), panoramaXMP(img.Bounds().Dx(), img.Bounds().Dy())...)
+	if len(payload)+2 > 0xFFFF {
+		return fmt.Errorf("XMP packet too large for a JPEG segment")
+	}
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	// Insert right after the SOI marker.
+	data := buf.Bytes()
+	for _, b := range [][]byte{data[:2], segment, payload, data[2:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
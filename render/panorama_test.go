@@ -0,0 +1,74 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestStitchEquirectangular(t *testing.T) {
+	const n = 8
+	palette := map[CubeFace]color.NRGBA{
+		CubeFront: {255, 0, 0, 255},
+		CubeRight: {0, 255, 0, 255},
+		CubeBack:  {0, 0, 255, 255},
+		CubeLeft:  {255, 255, 0, 255},
+		CubeUp:    {0, 255, 255, 255},
+		CubeDown:  {255, 0, 255, 255},
+	}
+	var faces [6]*image.NRGBA
+	for face, c := range palette {
+		img := image.NewNRGBA(image.Rect(0, 0, n, n))
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+		faces[face] = img
+	}
+
+	const W, H = 64, 32
+	pano := StitchEquirectangular(faces, W, H)
+
+	checks := []struct {
+		x, y int
+		face CubeFace
+	}{
+		{W / 2, H / 2, CubeFront},
+		{3 * W / 4, H / 2, CubeRight},
+		{0, H / 2, CubeBack},
+		{W / 4, H / 2, CubeLeft},
+		{W / 2, 0, CubeUp},
+		{W / 2, H - 1, CubeDown},
+	}
+	for _, c := range checks {
+		if got := pano.NRGBAAt(c.x, c.y); got != palette[c.face] {
+			t.Errorf("pixel (%d,%d) = %v, want %v face %v", c.x, c.y, got, c.face, palette[c.face])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodePanoramaPNG(&buf, pano); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`GPano:ProjectionType="equirectangular"`)) {
+		t.Error("panorama PNG is missing GPano metadata")
+	}
+	if _, err := png.Decode(&buf); err != nil {
+		t.Errorf("panorama PNG does not decode: %v", err)
+	}
+}
+
+func TestCubeFaceCameraSeams(t *testing.T) {
+	cam := NewCamera(20, 30, 5000, 60, 10, 20, 30)
+	const n = 16
+
+	// The right edge of the front face meets the left edge of the right face.
+	front := CubeFaceCamera(cam, CubeFront, n)
+	right := CubeFaceCamera(cam, CubeRight, n)
+	a := front.ComputeRay(n-0.5, n/2-0.5, n, n)
+	b := right.ComputeRay(-0.5, n/2-0.5, n, n)
+	if !vecNear(a, b, 1e-9) {
+		t.Fatalf("seam mismatch: %+v vs %+v", a, b)
+	}
+}
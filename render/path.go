@@ -0,0 +1,129 @@
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// Keyframe is a camera viewpoint on a CameraPath, with the same meaning of
+// the fields as the arguments of NewCamera.
+type Keyframe struct {
+	Time                 time.Time
+	Lat, Lon, Alt        float64
+	FOV, Tilt, Yaw, Roll float64
+}
+
+// CameraPath moves a camera smoothly through a sequence of keyframes.
+//
+// Latitude, longitude, altitude and field of view follow a Catmull-Rom spline
+// in time, so the camera passes through every keyframe without sudden changes
+// of speed. The orientation relative to the local nadir view (the tilt, yaw
+// and roll of NewCamera) is interpolated by slerp between neighbouring
+// keyframes, which turns the camera at a steady rate along the shortest arc.
+type CameraPath struct {
+	keys []Keyframe
+	rel  []vectors.Quat // attitude of each keyframe relative to its nadir view
+}
+
+// NewCameraPath builds a path through keys, which must be in strictly
+// increasing time order. Longitudes are unwrapped so the camera always takes
+// the shorter way between neighbouring keyframes, including across the
+// antimeridian.
+func NewCameraPath(keys []Keyframe) (*CameraPath, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("camera path needs at least one keyframe")
+	}
+
+	p := &CameraPath{
+		keys: make([]Keyframe, len(keys)),
+		rel:  make([]vectors.Quat, len(keys)),
+	}
+	copy(p.keys, keys)
+	for i := range p.keys {
+		k := &p.keys[i]
+		if i > 0 {
+			prev := p.keys[i-1]
+			if !k.Time.After(prev.Time) {
+				return nil, fmt.Errorf("keyframe %d at %s is not after the previous one", i, k.Time.Format(time.RFC3339))
+			}
+			for k.Lon-prev.Lon > 180 {
+				k.Lon -= 360
+			}
+			for k.Lon-prev.Lon < -180 {
+				k.Lon += 360
+			}
+		}
+
+		nadir := NewCamera(k.Lat, k.Lon, k.Alt, k.FOV, 0, 0, 0).Matrix()
+		view := NewCamera(k.Lat, k.Lon, k.Alt, k.FOV, k.Tilt, k.Yaw, k.Roll).Matrix()
+		p.rel[i] = vectors.QuatFromMat3(nadir.Transpose().Mul(view))
+	}
+	return p, nil
+}
+
+// Start returns the time of the first keyframe.
+func (p *CameraPath) Start() time.Time {
+	return p.keys[0].Time
+}
+
+// End returns the time of the last keyframe.
+func (p *CameraPath) End() time.Time {
+	return p.keys[len(p.keys)-1].Time
+}
+
+// Camera returns the camera at t. Before the first and after the last
+// keyframe the camera holds still at that keyframe.
+func (p *CameraPath) Camera(t time.Time) Camera {
+	n := len(p.keys)
+	if n == 1 || !t.After(p.keys[0].Time) {
+		return p.keyCamera(0)
+	}
+	if !t.Before(p.keys[n-1].Time) {
+		return p.keyCamera(n - 1)
+	}
+
+	i := 1
+	for p.keys[i].Time.Before(t) {
+		i++
+	}
+	a, b := p.keys[i-1], p.keys[i]
+	dt := b.Time.Sub(a.Time).Seconds()
+	s := t.Sub(a.Time).Seconds() / dt
+
+	spline := func(v func(Keyframe) float64) float64 {
+		return hermite(v(a), v(b), p.tangent(i-1, v)*dt, p.tangent(i, v)*dt, s)
+	}
+	lat := spline(func(k Keyframe) float64 { return k.Lat })
+	lon := spline(func(k Keyframe) float64 { return k.Lon })
+	alt := spline(func(k Keyframe) float64 { return k.Alt })
+	fov := spline(func(k Keyframe) float64 { return k.FOV })
+
+	nadir := NewCamera(lat, lon, alt, fov, 0, 0, 0)
+	rel := p.rel[i-1].Slerp(p.rel[i], s)
+	return NewCameraFromMatrix(nadir.Position, nadir.Matrix().Mul(rel.Mat3()), fov)
+}
+
+// keyCamera returns the camera exactly at keyframe i.
+func (p *CameraPath) keyCamera(i int) Camera {
+	k := p.keys[i]
+	return NewCamera(k.Lat, k.Lon, k.Alt, k.FOV, k.Tilt, k.Yaw, k.Roll)
+}
+
+// tangent returns the Catmull-Rom rate of change per second of v at
+// keyframe i: the slope between its neighbours, or the one-sided slope at
+// the ends of the path.
+func (p *CameraPath) tangent(i int, v func(Keyframe) float64) float64 {
+	lo, hi := max(i-1, 0), min(i+1, len(p.keys)-1)
+	a, b := p.keys[lo], p.keys[hi]
+	return (v(b) - v(a)) / b.Time.Sub(a.Time).Seconds()
+}
+
+// hermite evaluates the cubic Hermite spline from p0 to p1 with end tangents
+// m0 and m1 (per unit of s) at s in [0, 1].
+func hermite(p0, p1, m0, m1, s float64) float64 {
+	s2 := s * s
+	s3 := s2 * s
+	return (2*s3-3*s2+1)*p0 + (s3-2*s2+s)*m0 + (-2*s3+3*s2)*p1 + (s3-s2)*m1
+}
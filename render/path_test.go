@@ -0,0 +1,92 @@
+package render
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+)
+
+func TestCameraPathKeyframes(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 9, 0, 0, 0, time.UTC)
+	keys := []Keyframe{
+		{Time: t0, Lat: 10, Lon: 100, Alt: 8000, FOV: 60},
+		{Time: t0.Add(20 * time.Minute), Lat: 20, Lon: 120, Alt: 5000, FOV: 50, Tilt: 15, Yaw: 10},
+		{Time: t0.Add(60 * time.Minute), Lat: 35, Lon: 140, Alt: 2000, FOV: 40, Tilt: 30, Yaw: -20, Roll: 5},
+	}
+	path, err := NewCameraPath(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The path passes through every keyframe.
+	for _, k := range keys {
+		got := path.Camera(k.Time)
+		want := NewCamera(k.Lat, k.Lon, k.Alt, k.FOV, k.Tilt, k.Yaw, k.Roll)
+		if !vecNear(got.Position, want.Position, 1e-6) || !vecNear(got.Forward, want.Forward, 1e-9) ||
+			!vecNear(got.Up, want.Up, 1e-9) || got.FOVDeg != want.FOVDeg {
+			t.Errorf("camera at %s = %+v, want %+v", k.Time.Format(time.RFC3339), got, want)
+		}
+	}
+
+	// Between keyframes it moves smoothly: one second apart, the camera
+	// barely changes, also across a keyframe.
+	for _, at := range []time.Time{t0.Add(7 * time.Minute), keys[1].Time.Add(-time.Second / 2)} {
+		a, b := path.Camera(at), path.Camera(at.Add(time.Second))
+		if d := a.Position.Sub(b.Position).Norm(); d > 10 {
+			t.Errorf("camera jumped %.2f km in one second at %s", d, at.Format(time.RFC3339))
+		}
+		if d := a.Forward.Sub(b.Forward).Norm(); d > 1e-3 {
+			t.Errorf("view turned by %.4f rad in one second at %s", d, at.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestCameraPathSlerp(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 9, 0, 0, 0, time.UTC)
+	path, err := NewCameraPath([]Keyframe{
+		{Time: t0, Lat: 0, Lon: 0, Alt: 1000, FOV: 60},
+		{Time: t0.Add(time.Hour), Lat: 0, Lon: 0, Alt: 1000, FOV: 60, Yaw: 40},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Turning at a constant rate, halfway is half the yaw.
+	got := path.Camera(t0.Add(30 * time.Minute))
+	want := NewCamera(0, 0, 1000, 60, 0, 20, 0)
+	if !vecNear(got.Forward, want.Forward, 1e-9) || !vecNear(got.Up, want.Up, 1e-9) {
+		t.Errorf("halfway camera = %+v, want %+v", got, want)
+	}
+}
+
+func TestCameraPathAntimeridian(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 9, 0, 0, 0, time.UTC)
+	path, err := NewCameraPath([]Keyframe{
+		{Time: t0, Lat: 0, Lon: 170, Alt: 1000, FOV: 60},
+		{Time: t0.Add(time.Hour), Lat: 0, Lon: -170, Alt: 1000, FOV: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, lon, _ := earth.LatLonAlt(path.Camera(t0.Add(30 * time.Minute)).Position)
+	if math.Abs(math.Abs(lon)-180) > 1e-6 {
+		t.Errorf("halfway longitude = %.6f, want ±180", lon)
+	}
+}
+
+func TestCameraPathOrder(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 9, 0, 0, 0, time.UTC)
+	_, err := NewCameraPath([]Keyframe{
+		{Time: t0, FOV: 60, Alt: 1000},
+		{Time: t0, FOV: 60, Alt: 2000},
+	})
+	if err == nil {
+		t.Error("expected an error for keyframes at the same time")
+	}
+	if _, err := NewCameraPath(nil); err == nil {
+		t.Error("expected an error for an empty path")
+	}
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of a frame being rendered.
+type Progress struct {
+	Done    int64         // pixels finished
+	Total   int64         // pixels in the frame
+	Elapsed time.Duration // since the render started
+	ETA     time.Duration // estimated time remaining, from the rate so far
+}
+
+// Fraction returns the finished share of the frame in [0, 1].
+func (p Progress) Fraction() float64 {
+	if p.Total == 0 {
+		return 1
+	}
+	return float64(p.Done) / float64(p.Total)
+}
+
+// ProgressObserver receives progress updates from Render: each time another
+// percent of the frame is finished, the last one with Done == Total. Updates
+// are made one at a time, never concurrently, and hold up the worker that
+// makes them, so observers should return quickly.
+type ProgressObserver interface {
+	RenderProgress(Progress)
+}
+
+// ProgressFunc adapts a function to ProgressObserver.
+type ProgressFunc func(Progress)
+
+// RenderProgress calls f(p).
+func (f ProgressFunc) RenderProgress(p Progress) {
+	f(p)
+}
+
+// progressTracker turns finished pixels into Progress updates for an
+// observer. It is safe for concurrent use.
+type progressTracker struct {
+	observer ProgressObserver
+	start    time.Time
+	total    int64
+
+	mu   sync.Mutex
+	done int64
+	next int64 // pixel count of the next update
+}
+
+func newProgressTracker(observer ProgressObserver, total int64) *progressTracker {
+	return &progressTracker{observer: observer, start: time.Now(), total: total}
+}
+
+// add counts n more finished pixels.
+func (t *progressTracker) add(n int64) {
+	if t.observer == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done += n
+	t.update(t.done)
+}
+
+// update reports done pixels if another percent has been reached.
+func (t *progressTracker) update(done int64) {
+	if done < t.next {
+		return
+	}
+	percent := done * 100 / t.total
+	t.next = ((percent+1)*t.total + 99) / 100
+
+	elapsed := time.Since(t.start)
+	var eta time.Duration
+	if done > 0 {
+		eta = time.Duration(float64(elapsed) * float64(t.total-done) / float64(done))
+	}
+	t.observer.RenderProgress(Progress{Done: done, Total: t.total, Elapsed: elapsed, ETA: eta})
+}
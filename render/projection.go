@@ -0,0 +1,141 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// Projection maps image pixels to viewing directions for a camera.
+type Projection interface {
+	// Ray returns the normalized ECEF viewing direction for pixel (i,j) of a
+	// width×height image. i,j can be fractional (for supersampling). ok is
+	// false for pixels the projection does not cover, e.g. outside the image
+	// circle of a fisheye.
+	Ray(c Camera, i, j float64, width, height int) (dir vectors.Vec3, ok bool)
+}
+
+// Pinhole is the rectilinear perspective projection: r = f·tan(θ).
+type Pinhole struct{}
+
+// EquidistantFisheye maps angle from the optical axis linearly to image
+// radius: r = f·θ. The FOV can be up to 360°; pixels beyond it are black.
+type EquidistantFisheye struct{}
+
+// EquisolidFisheye preserves solid angle: r = 2f·sin(θ/2). The FOV can be up
+// to 360°; pixels beyond it are black.
+type EquisolidFisheye struct{}
+
+// Orthographic projects the view hemisphere onto the image plane:
+// r = f·sin(θ). The FOV is capped at 180°; pixels beyond it are black.
+type Orthographic struct{}
+
+// Equirectangular covers the full sphere around the camera, with longitude
+// along the image width (-180°..180°, Forward in the center) and latitude
+// along the height (+90° at the top, toward Up). The camera FOV is ignored.
+type Equirectangular struct{}
+
+// ParseProjection returns the projection for a name: pinhole, fisheye
+// (equidistant), equisolid, orthographic or equirectangular.
+func ParseProjection(name string) (Projection, error) {
+	switch name {
+	case "pinhole", "perspective":
+		return Pinhole{}, nil
+	case "fisheye", "equidistant":
+		return EquidistantFisheye{}, nil
+	case "equisolid":
+		return EquisolidFisheye{}, nil
+	case "orthographic":
+		return Orthographic{}, nil
+	case "equirectangular":
+		return Equirectangular{}, nil
+	}
+	return nil, fmt.Errorf("unknown projection %q", name)
+}
+
+func (Pinhole) Ray(c Camera, i, j float64, width, height int) (vectors.Vec3, bool) {
+	return c.ComputeRay(i, j, width, height), true
+}
+
+func (EquidistantFisheye) Ray(c Camera, i, j float64, width, height int) (vectors.Vec3, bool) {
+	halfFOV := math.Min(c.FOVDeg, 360) * math.Pi / 360.0
+	return radialRay(c, i, j, width, height, func(r float64) float64 {
+		return r * halfFOV
+	})
+}
+
+func (EquisolidFisheye) Ray(c Camera, i, j float64, width, height int) (vectors.Vec3, bool) {
+	halfFOV := math.Min(c.FOVDeg, 360) * math.Pi / 360.0
+	rEdge := math.Sin(halfFOV / 2)
+	return radialRay(c, i, j, width, height, func(r float64) float64 {
+		return 2 * math.Asin(r*rEdge)
+	})
+}
+
+func (Orthographic) Ray(c Camera, i, j float64, width, height int) (vectors.Vec3, bool) {
+	halfFOV := math.Min(c.FOVDeg, 180) * math.Pi / 360.0
+	rEdge := math.Sin(halfFOV)
+	return radialRay(c, i, j, width, height, func(r float64) float64 {
+		return math.Asin(r * rEdge)
+	})
+}
+
+func (Equirectangular) Ray(c Camera, i, j float64, width, height int) (vectors.Vec3, bool) {
+	d := equirectangularDir(i, j, width, height)
+	return c.toWorld(d.X, d.Y, d.Z), true
+}
+
+// equirectangularDir returns the camera-space direction (x right, y up,
+// z forward) of pixel (i,j) in a width×height equirectangular image.
+func equirectangularDir(i, j float64, width, height int) vectors.Vec3 {
+	// Pixel centers, so the left and right columns don't duplicate a meridian.
+	lon := ((i+0.5)/float64(width) - 0.5) * 2 * math.Pi
+	lat := (0.5 - (j+0.5)/float64(height)) * math.Pi
+
+	x := math.Cos(lat) * math.Sin(lon)
+	y := math.Sin(lat)
+	z := math.Cos(lat) * math.Cos(lon)
+	return vectors.Vec3{X: x, Y: y, Z: z}
+}
+
+// radialRay handles the radially symmetric projections. The pixel is mapped
+// to a radius r that is 1 at the edge of the FOV axis (see Camera.FOVAxis),
+// and theta(r) gives the angle from the optical axis. Pixels with r > 1 lie
+// outside the lens' field of view and are not covered.
+func radialRay(c Camera, i, j float64, width, height int, theta func(r float64) float64) (vectors.Vec3, bool) {
+	xNDC, yNDC := pixelToNDC(i, j, width, height)
+	sx, sy := c.FOVAxis.scale(width, height)
+	x, y := xNDC*sx, yNDC*sy
+
+	r := math.Hypot(x, y)
+	if r == 0 {
+		return c.Forward, true
+	}
+	if r > 1 {
+		return vectors.Vec3{}, false
+	}
+	t := theta(r)
+	sinT := math.Sin(t)
+	return c.toWorld(x/r*sinT, y/r*sinT, math.Cos(t)), true
+}
+
+// pixelToNDC maps pixel (i,j) to [-1, +1] (centered), flipping Y to make +up
+// in screen space.
+func pixelToNDC(i, j float64, width, height int) (float64, float64) {
+	w := float64(width)
+	h := float64(height)
+
+	xNDC := (i - (w-1)/2.0) / ((w - 1) / 2.0)
+	yNDC := -((j - (h-1)/2.0) / ((h - 1) / 2.0))
+	return xNDC, yNDC
+}
+
+// toWorld converts camera-space coordinates (x right, y up, z forward) to
+// an ECEF direction.
+func (c Camera) toWorld(x, y, z float64) vectors.Vec3 {
+	return c.Right.Scale(x).
+		Add(c.Up.Scale(y)).
+		Add(c.Forward.Scale(z)).
+		Normalize()
+}
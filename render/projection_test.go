@@ -0,0 +1,44 @@
+package render
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectionRays(t *testing.T) {
+	cam := NewCamera(10, 20, 1000, 180, 0, 0, 0)
+	const W, H = 101, 51
+
+	for _, name := range []string{"pinhole", "fisheye", "equisolid", "orthographic", "equirectangular"} {
+		proj, err := ParseProjection(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cam.Projection = proj
+
+		// The image center always looks along Forward. For the
+		// equirectangular projection, pixel centers are offset by half a
+		// pixel, so use the pixel corner shared by the four center pixels.
+		ci, cj := float64(W-1)/2, float64(H-1)/2
+		if name == "equirectangular" {
+			ci, cj = float64(W)/2-0.5, float64(H)/2-0.5
+		}
+		dir, ok := cam.Ray(ci, cj, W, H)
+		if !ok || !vecNear(dir, cam.Forward, 1e-9) {
+			t.Errorf("%s: center ray = %+v (ok=%v), want Forward %+v", name, dir, ok, cam.Forward)
+		}
+	}
+
+	// A 180° fisheye sees exactly sideways at the horizontal edge and
+	// nothing in the image corners.
+	for _, proj := range []Projection{EquidistantFisheye{}, EquisolidFisheye{}, Orthographic{}} {
+		cam.Projection = proj
+		dir, ok := cam.Ray(W-1, float64(H-1)/2, W, H)
+		if !ok || math.Abs(dir.Dot(cam.Forward)) > 1e-9 || dir.Dot(cam.Right) < 0.999999 {
+			t.Errorf("%T: edge ray = %+v (ok=%v), want Right", proj, dir, ok)
+		}
+		if _, ok := cam.Ray(0, 0, W, W); ok {
+			t.Errorf("%T: corner of a square frame should be outside the image circle", proj)
+		}
+	}
+}
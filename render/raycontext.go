@@ -21,9 +21,9 @@ type RayContext struct {
 
 	ViewDotNormal float64
 	theme         Theme
-	TexDay        Texture
-	TexNight      Texture
-	TexClouds     Texture
+	TexDay        TextureSampler
+	TexNight      TextureSampler
+	TexClouds     TextureSampler
 
 	HitsAtmosphere   bool
 	AtmosphereEntryT float64
@@ -32,15 +32,20 @@ type RayContext struct {
 	HitsEarthShadow   bool
 	EarthShadowEntryT float64
 	EarthShadowExitT  float64
+
+	// PixelAngle is the angular size of a sample in radians; Footprint the
+	// ground distance in km it covers at HitPoint, for choosing mipmap levels.
+	PixelAngle float64
+	Footprint  float64
 }
 
 func NewRayContext(
 	origin vectors.Vec3,
 	sunDir vectors.Vec3,
 	theme Theme,
-	texDay Texture,
-	texNight Texture,
-	texClouds Texture,
+	texDay TextureSampler,
+	texNight TextureSampler,
+	texClouds TextureSampler,
 ) *RayContext {
 	return &RayContext{
 		Origin:    origin,
@@ -66,10 +71,13 @@ func (c *RayContext) SetRayDirection(rayDirection vectors.Vec3) {
 		c.HitPoint = c.Origin.Add(c.RayDir.Scale(c.TEarth))
 		c.SurfaceNormal = c.HitPoint.Normalize()
 		c.ViewDotNormal = -c.SurfaceNormal.Dot(c.RayDir)
+		// Seen at an angle the footprint stretches; bound it near the limb.
+		c.Footprint = c.TEarth * c.PixelAngle / math.Max(c.ViewDotNormal, 0.05)
 	} else {
 		c.HitPoint = vectors.Zero()
 		c.SurfaceNormal = vectors.Zero()
 		c.ViewDotNormal = 0.0
+		c.Footprint = 0
 	}
 
 	// Step 3: Atmosphere intersection
@@ -1,10 +1,13 @@
 package render
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"math"
+	"runtime"
+	"time"
 
 	"github.com/echoflaresat/spacecam/colors"
 	"github.com/echoflaresat/spacecam/earth"
@@ -12,17 +15,6 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-type pixelJob struct {
-	X int
-	Y int
-}
-
-type pixelResult struct {
-	X    int
-	Y    int
-	RGBA color.NRGBA
-}
-
 var DayRim = colors.New(0.25, 0.60, 1.00, 1.0)
 var NightRim = colors.New(0.05, 0.07, 0.20, 0.5)
 var Warm = colors.New(1.02, 1.0, 0.98, 1.0)
@@ -34,6 +26,26 @@ type Theme struct {
 	Day      string
 	Night    string
 	Clouds   string
+
+	// Filters used to sample each texture; the zero value is FilterNearest.
+	DayFilter    Filter
+	NightFilter  Filter
+	CloudsFilter Filter
+
+	// Mipmaps samples the textures from image pyramids matched to the
+	// ground footprint of each pixel, cached next to the texture files.
+	Mipmaps bool
+
+	// TextureCacheMB bounds the decoded tiles of tiled TIFF textures held
+	// in memory, shared by all textures. 0 leaves caching to the TIFF
+	// decoder, which keeps a fixed number of tiles per file.
+	TextureCacheMB int
+
+	// CloudFrames, if any, replace Clouds with time-stamped cloud textures
+	// blended by the render time. CloudMotion makes the blend follow the
+	// motion between frames rather than cross-fade in place.
+	CloudFrames []TimedPath
+	CloudMotion bool
 }
 
 // Smoothstep performs a Hermite interpolation between 0 and 1 across [edge0, edge1].
@@ -92,9 +104,9 @@ func getLightIntensity(extendedSurfaceNormal, sunDir vectors.Vec3) float64 {
 // It blends day/night textures, clouds, specular, glow, and rim lighting.
 func RenderEarthSurface(ctx *RayContext) colors.Color4 {
 
-	CDay := ctx.TexDay.Sample(ctx.HitPoint)
-	CNight := ctx.TexNight.Sample(ctx.HitPoint)
-	CClouds := ctx.TexClouds.Sample(ctx.HitPoint)
+	CDay := sampleSurface(ctx.TexDay, ctx.HitPoint, ctx.Footprint)
+	CNight := sampleSurface(ctx.TexNight, ctx.HitPoint, ctx.Footprint)
+	CClouds := sampleSurface(ctx.TexClouds, ctx.HitPoint, ctx.Footprint)
 
 	light := getLightIntensity(ctx.SurfaceNormal, ctx.SunDir)
 
@@ -196,67 +208,142 @@ func GenerateSupersamplingOffsets(n int) [][2]float64 {
 	return out
 }
 
+// Renderer renders frames of one Theme. It loads the textures once, so batch
+// jobs rendering many frames don't decode them again for every frame, and
+// spreads each frame over a fixed number of workers. Close releases the
+// textures; a Renderer is safe for concurrent use until then.
+type Renderer struct {
+	theme      Theme
+	tex        Textures
+	numWorkers int
+}
+
+// Options describes the frame to render.
+type Options struct {
+	Width, Height int
+	Supersampling int              // samples per pixel axis; 0 means 1
+	Progress      ProgressObserver // notified as the frame fills in; may be nil
+
+	// Crop, if not empty, restricts rendering to this part of the
+	// Width×Height frame. Only its pixels are rendered, and they are the same
+	// as in the full frame.
+	Crop image.Rectangle
+
+	// Time is the moment rendered, for textures that change with the date
+	// (see TimeVaryingSampler). It may be zero if none does.
+	Time time.Time
+}
+
+// NewRenderer loads the textures of theme. numWorkers < 1 means one worker
+// per CPU.
+func NewRenderer(theme Theme, numWorkers int) (*Renderer, error) {
+	tex, err := LoadTextures(theme)
+	if err != nil {
+		return nil, err
+	}
+	return NewRendererWithTextures(theme, tex, numWorkers), nil
+}
+
+// NewRendererWithTextures returns a Renderer that samples tex instead of
+// loading the textures named by theme, e.g. images already in memory or
+// procedural samplers. The Renderer takes ownership of tex: Close closes
+// those that implement io.Closer.
+func NewRendererWithTextures(theme Theme, tex Textures, numWorkers int) *Renderer {
+	if numWorkers < 1 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	return &Renderer{theme: theme, tex: tex, numWorkers: numWorkers}
+}
+
+// TextureCache returns the tile cache of the textures, or nil if they have
+// none.
+func (r *Renderer) TextureCache() *TileCache {
+	return r.tex.Cache
+}
+
+// Close releases the textures. The Renderer must not be used afterwards.
+func (r *Renderer) Close() error {
+	return r.tex.Close()
+}
+
 // RenderScene mirrors your Python function. It loads three textures,
 // builds a RayContext, and raytraces the frame.
 func RenderScene(
 	camera Camera,
 	sunDir vectors.Vec3,
-	outSize int,
+	width, height int,
 	supersampling int,
 	theme Theme,
 	numWorkers int,
 ) (*image.NRGBA, error) {
 
-	texDay, err := LoadTexture(theme.Day)
-	if err != nil {
-		return nil, err
-	}
-	texNight, err := LoadTexture(theme.Night)
+	r, err := NewRenderer(theme, numWorkers)
 	if err != nil {
 		return nil, err
 	}
-	// Using day for clouds here, like your Python stub.
-	texClouds, err := LoadTexture(theme.Clouds)
-	if err != nil {
+	defer r.Close()
+
+	return r.Render(context.Background(), camera, sunDir, Options{
+		Width:         width,
+		Height:        height,
+		Supersampling: supersampling,
+	})
+}
+
+// Render raytraces one frame seen by camera with the sun in direction sunDir
+// (ECEF, unit length). With opts.Crop set, the image has the bounds of the
+// crop rectangle, like the SubImage of a full render. If ctx is cancelled or
+// its deadline passes, Render stops and returns an error wrapping ctx.Err(),
+// once all of its goroutines have exited.
+func (r *Renderer) Render(ctx context.Context, camera Camera, sunDir vectors.Vec3, opts Options) (*image.NRGBA, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
 	origin := camera.Position
 
-	ar := 1.0 // keep 9.0/16.0 if you switch aspect later
-	W, H := outSize, int(float64(outSize)*ar)
-	offsets := GenerateSupersamplingOffsets(supersampling)
+	W, H := opts.Width, opts.Height
+	offsets := GenerateSupersamplingOffsets(max(opts.Supersampling, 1))
 
-	img := image.NewNRGBA(image.Rect(0, 0, W, H))
-	jobs := make(chan pixelJob, 1024)
-	results := make(chan pixelResult, 1024)
+	region := image.Rect(0, 0, W, H)
+	if !opts.Crop.Empty() {
+		if !opts.Crop.In(region) {
+			return nil, fmt.Errorf("crop %v is outside the %dx%d frame", opts.Crop, W, H)
+		}
+		region = opts.Crop
+	}
 
-	g := errgroup.Group{}
+	tex, err := r.tex.atTime(opts.Time)
+	if err != nil {
+		return nil, err
+	}
 
-	// feeder (closes jobs)
+	img := image.NewNRGBA(region)
+	progress := newProgressTracker(opts.Progress, int64(region.Dx()*region.Dy()))
+	tiles := make(chan image.Rectangle, r.numWorkers)
+
+	// The first error, or ctx being done, cancels gctx and stops every
+	// stage; Wait then returns only after all of them have exited.
+	g, gctx := errgroup.WithContext(ctx)
+
+	// feeder (closes tiles)
 	g.Go(func() error {
-		return runFeeder(W, H, jobs)
+		return runFeeder(gctx, img.Bounds(), tiles)
 	})
 
-	for i := 0; i < numWorkers; i++ {
+	// Workers write their tiles straight into img; tiles never overlap.
+	for i := 0; i < r.numWorkers; i++ {
 		g.Go(func() error {
 			return runWorker(
-				origin, sunDir, theme, texDay, texNight, texClouds,
-				camera, W, H, ar, offsets,
-				jobs, results)
+				gctx, origin, sunDir, r.theme, tex.Day, tex.Night, tex.Clouds,
+				camera, W, H, offsets,
+				img, tiles, progress)
 		})
 	}
 
-	// writer (exits when results is closed)
-	g.Go(func() error {
-		return runWriter(img, results, W, H)
-	})
-
 	if err := g.Wait(); err != nil {
-		fmt.Printf("render aborted: %v\n", err)
-		return img, nil
+		return nil, fmt.Errorf("render aborted: %w", err)
 	}
-	println("done")
 	return img, nil
 }
 
@@ -440,74 +527,89 @@ func SunVisibleFraction(camPos, sunDir vectors.Vec3) float64 {
 
 	return visibleFraction
 }
-func runFeeder(W, H int, jobs chan<- pixelJob) error {
-	defer close(jobs)
-	for y := 0; y < H; y++ {
-		for x := 0; x < W; x++ {
-			jobs <- pixelJob{X: x, Y: y}
+
+// tileSize is the edge length in pixels of the square tiles a frame is split
+// into. A tile is small enough to balance the load across workers and large
+// enough that handing it out costs nothing next to rendering it.
+const tileSize = 32
+
+// runFeeder splits bounds into tiles in reading order.
+func runFeeder(ctx context.Context, bounds image.Rectangle, tiles chan<- image.Rectangle) error {
+	defer close(tiles)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += tileSize {
+		for x := bounds.Min.X; x < bounds.Max.X; x += tileSize {
+			tile := image.Rect(x, y, x+tileSize, y+tileSize).Intersect(bounds)
+			select {
+			case tiles <- tile:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 	}
 	return nil
 }
 
 func runWorker(
+	ctx context.Context,
 	origin vectors.Vec3,
 	sunDir vectors.Vec3,
 	theme Theme,
-	texDay Texture,
-	texNight Texture,
-	texClouds Texture,
+	texDay TextureSampler,
+	texNight TextureSampler,
+	texClouds TextureSampler,
 	camera Camera,
 	W, H int,
-	ar float64,
 	offsets [][2]float64,
-	jobs <-chan pixelJob,
-	results chan<- pixelResult,
+	img *image.NRGBA,
+	tiles <-chan image.Rectangle,
+	progress *progressTracker,
 ) error {
 	rc := NewRayContext(origin, sunDir, theme, texDay, texNight, texClouds)
 	rc.GlobalSunFraction = SunVisibleFraction(camera.Position, rc.SunDir)
 
-	for {
-		job, ok := <-jobs
-		if !ok {
-			break
+	proj := camera.projection()
+	// Each supersample covers a fraction of the pixel.
+	rc.PixelAngle = pixelAngle(camera, proj, W, H) / math.Sqrt(float64(len(offsets)))
+	for tile := range tiles {
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for x := tile.Min.X; x < tile.Max.X; x++ {
+				img.SetNRGBA(x, y, renderPixel(rc, camera, proj, x, y, W, H, offsets))
+			}
 		}
-		rgba := renderPixel(rc, camera, job.X, job.Y, W, H, ar, offsets)
-		results <- pixelResult{X: job.X, Y: job.Y, RGBA: rgba}
+		progress.add(int64(tile.Dx() * tile.Dy()))
 	}
 	return nil
 }
 
-func runWriter(
-	img *image.NRGBA,
-	results <-chan pixelResult,
-	W, H int,
-) error {
-	completed := int64(0)
-	total := int64(W * H)
-	nextMileStone := 0
-	for completed < total {
-		res := <-results
-		img.SetNRGBA(res.X, res.Y, res.RGBA)
-		completed += 1
-		progress := (completed * 100) / total
-		if int(progress) >= nextMileStone {
-			fmt.Printf(" %3d%% ", nextMileStone)
-			nextMileStone += 10
-		}
+// pixelAngle returns the angle in radians between the rays of two adjacent
+// pixels at the image center, or 0 if the projection doesn't cover them.
+func pixelAngle(camera Camera, proj Projection, W, H int) float64 {
+	cx, cy := float64(W)/2, float64(H)/2
+	a, okA := proj.Ray(camera, cx, cy, W, H)
+	b, okB := proj.Ray(camera, cx+1, cy, W, H)
+	if !okA || !okB {
+		return 0
 	}
-	return nil
+	return math.Acos(Clip(a.Dot(b), -1, 1))
 }
 
-func renderPixel(ctx *RayContext, camera Camera, x, y, W, H int, ar float64, offsets [][2]float64) color.NRGBA {
+func renderPixel(ctx *RayContext, camera Camera, proj Projection, x, y, W, H int, offsets [][2]float64) color.NRGBA {
 	colorAccum := colors.Color4{}
 
 	for _, off := range offsets {
 		dx, dy := off[0], off[1]
 
-		// If ComputeRay or SetRayDirection read camera only, it's safe.
+		// If Ray or SetRayDirection read camera only, it's safe.
 		// If they mutate shared state, clone/guard similarly.
-		rayDir := camera.ComputeRay(float64(x)+dx, (float64(y)+dy)*ar, W, H)
+		rayDir, ok := proj.Ray(camera, float64(x)+dx, float64(y)+dy, W, H)
+		if !ok {
+			// outside the projection's coverage, e.g. beyond the fisheye circle
+			colorAccum = colorAccum.Add(colors.Black())
+			continue
+		}
 
 		ctx.SetRayDirection(rayDir)
 
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/vectors"
+	"golang.org/x/sync/errgroup"
+)
+
+// renderPerPixel is the previous scheduler, kept as a baseline: a feeder
+// sends every pixel through a channel to the workers, which send each result
+// to a single writer goroutine.
+func (r *Renderer) renderPerPixel(camera Camera, opts Options) *image.NRGBA {
+	W, H := opts.Width, opts.Height
+	offsets := GenerateSupersamplingOffsets(max(opts.Supersampling, 1))
+	img := image.NewNRGBA(image.Rect(0, 0, W, H))
+
+	type pixelJob struct{ X, Y int }
+	type pixelResult struct {
+		X, Y int
+		RGBA color.NRGBA
+	}
+	jobs := make(chan pixelJob, 1024)
+	results := make(chan pixelResult, 1024)
+
+	g := errgroup.Group{}
+	g.Go(func() error {
+		defer close(jobs)
+		for y := 0; y < H; y++ {
+			for x := 0; x < W; x++ {
+				jobs <- pixelJob{X: x, Y: y}
+			}
+		}
+		return nil
+	})
+	sunDir := benchSunDir()
+	for i := 0; i < r.numWorkers; i++ {
+		g.Go(func() error {
+			rc := NewRayContext(camera.Position, sunDir, r.theme, r.tex.Day, r.tex.Night, r.tex.Clouds)
+			rc.GlobalSunFraction = SunVisibleFraction(camera.Position, rc.SunDir)
+			proj := camera.projection()
+			for job := range jobs {
+				rgba := renderPixel(rc, camera, proj, job.X, job.Y, W, H, offsets)
+				results <- pixelResult{X: job.X, Y: job.Y, RGBA: rgba}
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		for n := 0; n < W*H; n++ {
+			res := <-results
+			img.SetNRGBA(res.X, res.Y, res.RGBA)
+		}
+		return nil
+	})
+	g.Wait()
+	return img
+}
+
+func benchSunDir() vectors.Vec3 {
+	return earth.SunDirectionECEF(time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC))
+}
+
+func TestRenderTilesMatchPerPixel(t *testing.T) {
+	r := testRenderer(4)
+	camera := NewCamera(20, 30, 8000, 60, 0, 0, 0)
+	// Not a multiple of the tile size, so the edge tiles are partial.
+	opts := Options{Width: 75, Height: 41, Supersampling: 2}
+
+	tiled, err := r.Render(context.Background(), camera, benchSunDir(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tiled.Pix) != string(r.renderPerPixel(camera, opts).Pix) {
+		t.Error("tiled render differs from the per-pixel pipeline")
+	}
+}
+
+// BenchmarkRender compares the tile scheduler with the per-pixel channel
+// pipeline it replaced. Run with -benchtime=10x for the larger frames.
+func BenchmarkRender(b *testing.B) {
+	camera := NewCamera(20, 30, 8000, 60, 0, 0, 0)
+	for _, size := range []int{256, 1024} {
+		opts := Options{Width: size, Height: size}
+		r := testRenderer(runtime.GOMAXPROCS(0))
+
+		b.Run(fmt.Sprintf("tiles/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(size * size)) // MB/s reads as megapixels per second
+			for i := 0; i < b.N; i++ {
+				if _, err := r.Render(context.Background(), camera, benchSunDir(), opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("per-pixel/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(size * size))
+			for i := 0; i < b.N; i++ {
+				r.renderPerPixel(camera, opts)
+			}
+		})
+	}
+}
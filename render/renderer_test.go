@@ -0,0 +1,152 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"image"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/colors"
+	"github.com/echoflaresat/spacecam/earth"
+)
+
+// testRenderer returns a Renderer with small in-memory textures.
+func testRenderer(numWorkers int) *Renderer {
+	tex := func() Texture {
+		img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+		for i := range img.Pix {
+			img.Pix[i] = byte(i)
+		}
+		return Texture{Width: 64, Height: 32, img: img}
+	}
+	theme := Theme{
+		DaySky:   colors.New(0.25, 0.60, 1.00, 0.5),
+		NightSky: colors.New(0.043, 0.047, 0.063, 0.5),
+		Warm:     colors.New(1.02, 1.0, 0.98, 1.0),
+	}
+	return &Renderer{
+		theme:      theme,
+		tex:        Textures{Day: tex(), Night: tex(), Clouds: tex()},
+		numWorkers: numWorkers,
+	}
+}
+
+func TestRenderCancel(t *testing.T) {
+	r := testRenderer(4)
+	camera := NewCamera(0, 0, 8000, 60, 0, 0, 0)
+	sunDir := earth.SunDirectionECEF(time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC))
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.Render(ctx, camera, sunDir, Options{Width: 64, Height: 64}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render with cancelled context: err = %v, want context.Canceled", err)
+	}
+
+	// A frame far too large to finish before the deadline.
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	img, err := r.Render(ctx, camera, sunDir, Options{Width: 4000, Height: 4000, Supersampling: 2})
+	if !errors.Is(err, context.DeadlineExceeded) || img != nil {
+		t.Errorf("Render past deadline: img = %v, err = %v, want nil and context.DeadlineExceeded", img != nil, err)
+	}
+
+	// Render waits for its goroutines, so none are left behind.
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines still running after cancellation", after-before)
+	}
+}
+
+func TestRenderComplete(t *testing.T) {
+	r := testRenderer(3)
+	camera := NewCamera(0, 0, 8000, 60, 0, 0, 0)
+	sunDir := earth.SunDirectionECEF(time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC))
+
+	opts := Options{Width: 40, Height: 30}
+	a, err := r.Render(context.Background(), camera, sunDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.Render(context.Background(), camera, sunDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Bounds() != image.Rect(0, 0, 40, 30) {
+		t.Fatalf("bounds = %v, want 40x30", a.Bounds())
+	}
+	if string(a.Pix) != string(b.Pix) {
+		t.Error("rendering the same frame twice gave different images")
+	}
+}
+
+func TestRenderProgress(t *testing.T) {
+	r := testRenderer(4)
+	camera := NewCamera(0, 0, 8000, 60, 0, 0, 0)
+	sunDir := earth.SunDirectionECEF(time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC))
+
+	var updates []Progress
+	opts := Options{Width: 37, Height: 23, Progress: ProgressFunc(func(p Progress) {
+		updates = append(updates, p)
+	})}
+	if _, err := r.Render(context.Background(), camera, sunDir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updates) == 0 || len(updates) > 101 {
+		t.Fatalf("got %d progress updates, want 1 to 101", len(updates))
+	}
+	for i, p := range updates {
+		if p.Total != 37*23 {
+			t.Errorf("update %d: Total = %d, want %d", i, p.Total, 37*23)
+		}
+		if i > 0 && p.Done <= updates[i-1].Done {
+			t.Errorf("update %d: Done = %d, not after %d", i, p.Done, updates[i-1].Done)
+		}
+	}
+	if last := updates[len(updates)-1]; last.Done != last.Total || last.ETA != 0 {
+		t.Errorf("last update = %+v, want Done == Total and no ETA", last)
+	}
+}
+
+func TestRenderCrop(t *testing.T) {
+	r := testRenderer(4)
+	camera := NewCamera(0, 0, 8000, 60, 0, 0, 0)
+	sunDir := earth.SunDirectionECEF(time.Date(2024, 8, 8, 12, 0, 0, 0, time.UTC))
+
+	opts := Options{Width: 90, Height: 70, Supersampling: 2}
+	full, err := r.Render(context.Background(), camera, sunDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, crop := range []image.Rectangle{
+		image.Rect(0, 0, 90, 70),
+		image.Rect(13, 7, 61, 44),
+		image.Rect(89, 0, 90, 70),
+	} {
+		opts.Crop = crop
+		img, err := r.Render(context.Background(), camera, sunDir, opts)
+		if err != nil {
+			t.Fatalf("crop %v: %v", crop, err)
+		}
+		if img.Bounds() != crop {
+			t.Errorf("crop %v: bounds = %v", crop, img.Bounds())
+			continue
+		}
+		want := full.SubImage(crop).(*image.NRGBA)
+		for y := crop.Min.Y; y < crop.Max.Y; y++ {
+			if a, b := img.Pix[img.PixOffset(crop.Min.X, y):img.PixOffset(crop.Max.X-1, y)+4],
+				want.Pix[want.PixOffset(crop.Min.X, y):want.PixOffset(crop.Max.X-1, y)+4]; string(a) != string(b) {
+				t.Errorf("crop %v: row %d differs from the full render", crop, y)
+				break
+			}
+		}
+	}
+
+	opts.Crop = image.Rect(80, 60, 100, 80)
+	if _, err := r.Render(context.Background(), camera, sunDir, opts); err == nil {
+		t.Error("expected an error for a crop outside the frame")
+	}
+}
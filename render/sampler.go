@@ -0,0 +1,106 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/echoflaresat/spacecam/colors"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// TextureSampler is a source of surface color over the globe. Texture is the
+// file-backed implementation; in-memory images, procedural generators or
+// composites of several regional images can be plugged in by implementing
+// it. Samplers are called from every render worker at once, so they must be
+// safe for concurrent use.
+type TextureSampler interface {
+	// Sample returns the color at the direction of P (ECEF), which need
+	// not be normalized.
+	Sample(P vectors.Vec3) colors.Color4
+	// SampleLatLon returns the color at a geocentric latitude and longitude
+	// in degrees.
+	SampleLatLon(lat, lon float64) colors.Color4
+}
+
+// FootprintSampler is a TextureSampler that can filter for the ground
+// footprint of a pixel, such as a Texture with mipmaps.
+type FootprintSampler interface {
+	TextureSampler
+	// SampleFootprint samples at P for a pixel that covers footprint km of
+	// ground.
+	SampleFootprint(P vectors.Vec3, footprint float64) colors.Color4
+}
+
+// sampleSurface samples s at P, filtered for footprint if s supports it.
+func sampleSurface(s TextureSampler, P vectors.Vec3, footprint float64) colors.Color4 {
+	if fs, ok := s.(FootprintSampler); ok {
+		return fs.SampleFootprint(P, footprint)
+	}
+	return s.Sample(P)
+}
+
+// SamplerFunc adapts a function of latitude and longitude in degrees, such as
+// a procedural generator, to TextureSampler.
+type SamplerFunc func(lat, lon float64) colors.Color4
+
+// Sample calls f at the latitude and longitude of P.
+func (f SamplerFunc) Sample(P vectors.Vec3) colors.Color4 {
+	return f(latLon(P))
+}
+
+// SampleLatLon calls f(lat, lon).
+func (f SamplerFunc) SampleLatLon(lat, lon float64) colors.Color4 {
+	return f(lat, lon)
+}
+
+// NewImageTexture returns a Texture sampling an equirectangular image already
+// in memory. The image must not be modified while it is in use.
+func NewImageTexture(img image.Image, filter Filter) Texture {
+	b := img.Bounds()
+	if b.Min != (image.Point{}) {
+		img = translated{img, b.Min}
+	}
+	return Texture{Width: b.Dx(), Height: b.Dy(), Filter: filter, img: img}
+}
+
+// translated moves an image so that its bounds start at the origin.
+type translated struct {
+	image.Image
+	min image.Point
+}
+
+func (t translated) Bounds() image.Rectangle {
+	return t.Image.Bounds().Sub(t.min)
+}
+
+func (t translated) At(x, y int) color.Color {
+	return t.Image.At(x+t.min.X, y+t.min.Y)
+}
+
+// latLon returns the geocentric latitude and longitude of P in degrees.
+func latLon(P vectors.Vec3) (lat, lon float64) {
+	lat = math.Atan2(P.Z, math.Hypot(P.X, P.Y)) * 180 / math.Pi
+	lon = math.Atan2(P.Y, P.X) * 180 / math.Pi
+	return lat, lon
+}
+
+// unitVector returns the ECEF unit vector at a latitude and longitude in
+// degrees.
+func unitVector(lat, lon float64) vectors.Vec3 {
+	lat, lon = lat*math.Pi/180, lon*math.Pi/180
+	return vectors.Vec3{
+		X: math.Cos(lat) * math.Cos(lon),
+		Y: math.Cos(lat) * math.Sin(lon),
+		Z: math.Sin(lat),
+	}
+}
+
+// closeSampler closes s if it holds resources, like a file-backed Texture.
+func closeSampler(s TextureSampler) error {
+	if c, ok := s.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
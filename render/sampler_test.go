@@ -0,0 +1,61 @@
+package render
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/echoflaresat/spacecam/colors"
+)
+
+func TestSamplerFuncLatLon(t *testing.T) {
+	var gotLat, gotLon float64
+	f := SamplerFunc(func(lat, lon float64) colors.Color4 {
+		gotLat, gotLon = lat, lon
+		return colors.White()
+	})
+	tex := gradientTexture(FilterNearest)
+	f.Sample(texturePoint(tex, 3, 0.5)) // lon 90°, lat 45°
+	if math.Abs(gotLat-45) > 1e-9 || math.Abs(gotLon-90) > 1e-9 {
+		t.Errorf("Sample passed lat, lon = %g, %g, want 45, 90", gotLat, gotLon)
+	}
+}
+
+func TestImageTextureSubImage(t *testing.T) {
+	// The texture is the 4×2 gradient placed inside a larger image.
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	src := gradientTexture(FilterNearest)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x+5, y+3, src.img.At(x, y))
+		}
+	}
+	tex := NewImageTexture(img.SubImage(image.Rect(5, 3, 9, 5)), FilterBilinear)
+	if tex.Width != 4 || tex.Height != 2 {
+		t.Fatalf("size = %dx%d, want 4x2", tex.Width, tex.Height)
+	}
+	src.Filter = FilterBilinear
+	for _, lon := range []float64{-170, -45, 0, 60, 179} {
+		if got, want := tex.SampleLatLon(10, lon), src.SampleLatLon(10, lon); got != want {
+			t.Errorf("SampleLatLon(10, %g) = %v, want %v", lon, got, want)
+		}
+	}
+}
+
+func TestRenderProceduralTextures(t *testing.T) {
+	red := SamplerFunc(func(lat, lon float64) colors.Color4 { return colors.Red() })
+	black := SamplerFunc(func(lat, lon float64) colors.Color4 { return colors.Black() })
+	r := NewRendererWithTextures(testRenderer(1).theme, Textures{Day: red, Night: black, Clouds: black}, 2)
+	defer r.Close()
+
+	camera := NewCamera(0, 0, 8000, 30, 0, 0, 0)
+	img, err := r.Render(context.Background(), camera, camera.Position.Normalize(), Options{Width: 16, Height: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sub-satellite point faces the sun, so the day texture shows.
+	if c := img.NRGBAAt(8, 8); c.R <= c.G || c.R <= c.B {
+		t.Errorf("center pixel = %v, want red", c)
+	}
+}
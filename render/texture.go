@@ -1,11 +1,14 @@
 package render
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	_ "image/jpeg" // register JPEG format with image.Decode
 	_ "image/png"  // register PNG format with image.Decode
 	"math"
 	"os"
+	"time"
 
 	"github.com/echoflaresat/spacecam/colors"
 	"github.com/echoflaresat/spacecam/vectors"
@@ -16,8 +19,44 @@ import (
 type Texture struct {
 	Width  int
 	Height int
+	Filter Filter
 	img    image.Image
 	file   *os.File
+	mips   []Texture // image pyramid, each level half the size of the one before
+}
+
+// Filter selects how a Texture is sampled between texel centers.
+type Filter int
+
+const (
+	FilterNearest  Filter = iota // the texel the point falls in
+	FilterBilinear               // linear blend of the 2×2 nearest texels
+	FilterBicubic                // Catmull-Rom spline through the 4×4 nearest texels
+)
+
+// ParseFilter parses "nearest", "bilinear" or "bicubic".
+func ParseFilter(s string) (Filter, error) {
+	switch s {
+	case "nearest":
+		return FilterNearest, nil
+	case "bilinear":
+		return FilterBilinear, nil
+	case "bicubic":
+		return FilterBicubic, nil
+	}
+	return 0, fmt.Errorf("unknown texture filter %q (want nearest, bilinear or bicubic)", s)
+}
+
+func (f Filter) String() string {
+	switch f {
+	case FilterNearest:
+		return "nearest"
+	case FilterBilinear:
+		return "bilinear"
+	case FilterBicubic:
+		return "bicubic"
+	}
+	return fmt.Sprintf("Filter(%d)", int(f))
 }
 
 func LoadImage(f *os.File) (image.Image, error) {
@@ -30,19 +69,33 @@ func LoadImage(f *os.File) (image.Image, error) {
 	return img, err
 }
 
-// NewTexture constructs a Texture from a raw uint8 slice (H × W × 3).
-// Data must be laid out row-major, tightly packed.
+// LoadTexture loads the image at path. TIFFs are read lazily by the TIFF
+// decoder, which keeps recently used tiles of each file decoded.
 func LoadTexture(path string) (Texture, error) {
+	return LoadTextureCached(path, nil)
+}
+
+// LoadTextureCached is LoadTexture, except that tiled TIFFs are read through
+// cache, which bounds the memory of decoded tiles across all textures
+// sharing it. A nil cache leaves caching to the TIFF decoder.
+func LoadTextureCached(path string, cache *TileCache) (Texture, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return Texture{}, err
 	}
 
-	img, err := tiff.Decode(f)
+	var img image.Image
+	err = errNotTiled
+	if cache != nil {
+		img, err = openCachedTIFF(f, cache)
+	}
+	if errors.Is(err, errNotTiled) {
+		img, err = tiff.Decode(f)
 
-	// fallback to image codecs
-	if err != nil {
-		img, _, err = image.Decode(f)
+		// fallback to image codecs
+		if err != nil {
+			img, _, err = image.Decode(f)
+		}
 	}
 
 	if err != nil {
@@ -59,26 +112,183 @@ func LoadTexture(path string) (Texture, error) {
 }
 
 func (t Texture) Close() error {
+	err := t.closeMipmaps()
 	if t.file != nil {
-		return t.file.Close()
+		return errors.Join(t.file.Close(), err)
 	}
-	return nil
+	return err
 }
 
-func (t Texture) Sample4(P vectors.Vec3) (colors.Color4, colors.Color4, colors.Color4, colors.Color4) {
-	x, y := t.getXY(P)
-	return t.getColorAtXY(x, y),
-		t.getColorAtXY(x+1, y),
-		t.getColorAtXY(x, y+1),
-		t.getColorAtXY(x+1, y+1)
+// Textures holds the day, night and cloud textures of a Theme, loaded once so
+// that several frames can share them.
+type Textures struct {
+	Day    TextureSampler
+	Night  TextureSampler
+	Clouds TextureSampler
+
+	// Cache, if not nil, holds the decoded tiles of the tiled TIFFs among
+	// the textures.
+	Cache *TileCache
+}
+
+// LoadTextures loads the textures named by theme, with their image pyramids
+// if theme.Mipmaps is set. A Day pattern like world.2004%02d.tif loads a
+// MonthlyTexture, and theme.CloudFrames a TimedTexture.
+func LoadTextures(theme Theme) (Textures, error) {
+	var cache *TileCache
+	if theme.TextureCacheMB > 0 {
+		cache = NewTileCache(int64(theme.TextureCacheMB) << 20)
+	}
+	load := func(filter Filter) func(string) (Texture, error) {
+		return func(path string) (Texture, error) {
+			return loadThemeTexture(path, filter, theme.Mipmaps, cache)
+		}
+	}
+
+	var day TextureSampler
+	var err error
+	if IsMonthlyPattern(theme.Day) {
+		day, err = LoadMonthlyTexture(theme.Day, load(theme.DayFilter))
+	} else {
+		day, err = load(theme.DayFilter)(theme.Day)
+	}
+	if err != nil {
+		return Textures{}, err
+	}
+	night, err := load(theme.NightFilter)(theme.Night)
+	if err != nil {
+		closeSampler(day)
+		return Textures{}, err
+	}
+	var clouds TextureSampler
+	if len(theme.CloudFrames) > 0 {
+		clouds, err = LoadTimedTexture(theme.CloudFrames, theme.CloudMotion, load(theme.CloudsFilter))
+	} else {
+		clouds, err = load(theme.CloudsFilter)(theme.Clouds)
+	}
+	if err != nil {
+		closeSampler(day)
+		night.Close()
+		return Textures{}, err
+	}
+	return Textures{Day: day, Night: night, Clouds: clouds, Cache: cache}, nil
 }
 
-// Sample maps the 3D vector P (ECEF) to texture coordinates and returns a color.Color4.
-// Equivalent to the Python version: lon-lat projection, no interpolation.
+// loadThemeTexture loads the texture at path with filter, and its image
+// pyramid if mipmaps is set.
+func loadThemeTexture(path string, filter Filter, mipmaps bool, cache *TileCache) (Texture, error) {
+	tex, err := LoadTextureCached(path, cache)
+	if err != nil {
+		return Texture{}, err
+	}
+	tex.Filter = filter
+	if mipmaps {
+		if err := tex.loadMipmaps(path, cache); err != nil {
+			tex.Close()
+			return Texture{}, err
+		}
+	}
+	return tex, nil
+}
+
+// atTime resolves the time-varying textures to their samplers at when.
+func (t Textures) atTime(when time.Time) (Textures, error) {
+	for _, s := range []*TextureSampler{&t.Day, &t.Night, &t.Clouds} {
+		tv, ok := (*s).(TimeVaryingSampler)
+		if !ok {
+			continue
+		}
+		if when.IsZero() {
+			return t, errors.New("time-varying textures need Options.Time")
+		}
+		*s = tv.AtTime(when)
+	}
+	return t, nil
+}
+
+// Close releases the files backing the textures, if any.
+func (t Textures) Close() error {
+	errDay := closeSampler(t.Day)
+	errNight := closeSampler(t.Night)
+	errClouds := closeSampler(t.Clouds)
+	return errors.Join(errDay, errNight, errClouds)
+}
+
+// Sample maps the 3D vector P (ECEF) to texture coordinates and returns the
+// color there, filtered with t.Filter.
 func (t Texture) Sample(P vectors.Vec3) colors.Color4 {
+	switch t.Filter {
+	case FilterBilinear:
+		return t.sampleBilinear(t.getUV(P))
+	case FilterBicubic:
+		return t.sampleBicubic(t.getUV(P))
+	}
 	return t.getColorAtXY(t.getXY(P))
 }
 
+// SampleLatLon returns the color at a latitude and longitude in degrees.
+func (t Texture) SampleLatLon(lat, lon float64) colors.Color4 {
+	return t.Sample(unitVector(lat, lon))
+}
+
+// sampleBilinear blends the 2×2 texels around (u, v).
+func (t Texture) sampleBilinear(u, v float64) colors.Color4 {
+	x, fx := splitTexel(u)
+	y, fy := splitTexel(v)
+	top := t.texel(x, y).Mix(t.texel(x+1, y), fx)
+	bottom := t.texel(x, y+1).Mix(t.texel(x+1, y+1), fx)
+	return top.Mix(bottom, fy)
+}
+
+// sampleBicubic filters the 4×4 texels around (u, v) with the Catmull-Rom
+// spline. The spline overshoots at hard edges, so the result is clamped.
+func (t Texture) sampleBicubic(u, v float64) colors.Color4 {
+	x, fx := splitTexel(u)
+	y, fy := splitTexel(v)
+	wx := catmullRomWeights(fx)
+	wy := catmullRomWeights(fy)
+
+	var c colors.Color4
+	for j := 0; j < 4; j++ {
+		var row colors.Color4
+		for i := 0; i < 4; i++ {
+			row = row.Add(t.texel(x+i-1, y+j-1).Scale(wx[i]))
+		}
+		c = c.Add(row.Scale(wy[j]))
+	}
+	return c.Clamp01()
+}
+
+// splitTexel splits a continuous texture coordinate into the texel whose
+// center is at or before it and the fraction of the way to the next center.
+func splitTexel(u float64) (int, float64) {
+	f := math.Floor(u - 0.5)
+	return int(f), u - 0.5 - f
+}
+
+// catmullRomWeights returns the weights of the texels at -1, 0, 1 and 2 for a
+// point the fraction f of the way from texel 0 to texel 1.
+func catmullRomWeights(f float64) [4]float64 {
+	f2, f3 := f*f, f*f*f
+	return [4]float64{
+		-0.5*f3 + f2 - 0.5*f,
+		1.5*f3 - 2.5*f2 + 1,
+		-1.5*f3 + 2*f2 + 0.5*f,
+		0.5*f3 - 0.5*f2,
+	}
+}
+
+// texel returns the texel at (x, y), wrapping x around the antimeridian and
+// clamping y at the poles.
+func (t Texture) texel(x, y int) colors.Color4 {
+	x %= t.Width
+	if x < 0 {
+		x += t.Width
+	}
+	y = min(max(y, 0), t.Height-1)
+	return colors.FromStandardColor(t.img.At(x, y))
+}
+
 func (t Texture) getColorAtXY(x, y int) colors.Color4 {
 	if x < 0 {
 		x = 0
@@ -95,6 +305,24 @@ func (t Texture) getColorAtXY(x, y int) colors.Color4 {
 	return colors.FromStandardColor(c)
 }
 
+// getUV maps P to continuous texture coordinates: u runs from 0 to Width
+// eastward from the antimeridian, v from 0 at the north pole to Height at the
+// south pole, and texel (x, y) covers [x, x+1)×[y, y+1).
+func (t Texture) getUV(P vectors.Vec3) (float64, float64) {
+	lat := math.Atan2(P.Z, math.Hypot(P.X, P.Y))
+	lon := math.Atan2(P.Y, P.X)
+
+	u := (0.5 + lon/(2*math.Pi)) * float64(t.Width)
+	if u >= float64(t.Width) {
+		u -= float64(t.Width)
+	}
+	v := (0.5 - lat/math.Pi) * float64(t.Height)
+	return u, v
+}
+
+// getXY is the nearest-neighbour lookup of the original renderer. It spreads
+// the texture over Width-1 texels rather than Width, which is kept so that
+// FilterNearest renders exactly as before.
 func (t Texture) getXY(P vectors.Vec3) (int, int) {
 	px, py, pz := P.X, P.Y, P.Z
 
@@ -0,0 +1,90 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// gradientTexture is a 4×2 texture whose red channel steps by 0.2 per column
+// and green channel by 1 per row.
+func gradientTexture(filter Filter) Texture {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(51 * x), uint8(255 * y), 0, 255})
+		}
+	}
+	return Texture{Width: 4, Height: 2, Filter: filter, img: img}
+}
+
+// texturePoint returns the ECEF direction that t maps to texture coordinates
+// (u, v).
+func texturePoint(t Texture, u, v float64) vectors.Vec3 {
+	lon := (u/float64(t.Width) - 0.5) * 2 * math.Pi
+	lat := (0.5 - v/float64(t.Height)) * math.Pi
+	return vectors.Vec3{
+		X: math.Cos(lat) * math.Cos(lon),
+		Y: math.Cos(lat) * math.Sin(lon),
+		Z: math.Sin(lat),
+	}
+}
+
+func TestSampleFiltered(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		u, v   float64
+		r, g   float64
+	}{
+		{"bilinear texel center", FilterBilinear, 1.5, 0.5, 0.2, 0},
+		{"bilinear between columns", FilterBilinear, 2.25, 0.5, 0.35, 0},
+		{"bilinear between rows", FilterBilinear, 1.5, 1.25, 0.2, 0.75},
+		// A quarter texel west of column 0's center blends in a quarter of
+		// column 3, on the other side of the antimeridian.
+		{"bilinear across antimeridian", FilterBilinear, 0.25, 0.5, 0.15, 0},
+		{"bilinear north pole", FilterBilinear, 1.5, 0, 0.2, 0},
+		{"bilinear south pole", FilterBilinear, 1.5, 2, 0.2, 1},
+		{"bicubic texel center", FilterBicubic, 2.5, 1.5, 0.4, 1},
+		{"bicubic north pole", FilterBicubic, 2.5, 0, 0.4, 0},
+	}
+	for _, tt := range tests {
+		tex := gradientTexture(tt.filter)
+		c := tex.Sample(texturePoint(tex, tt.u, tt.v))
+		if math.Abs(c.R-tt.r) > 1e-6 || math.Abs(c.G-tt.g) > 1e-6 {
+			t.Errorf("%s: Sample(%g, %g) = %.4f,%.4f, want %.4f,%.4f", tt.name, tt.u, tt.v, c.R, c.G, tt.r, tt.g)
+		}
+	}
+}
+
+func TestSampleBicubicSmooth(t *testing.T) {
+	tex := gradientTexture(FilterBicubic)
+	// Within a linear ramp, Catmull-Rom reproduces the ramp.
+	for _, u := range []float64{1.5, 1.7, 2, 2.3, 2.5} {
+		c := tex.Sample(texturePoint(tex, u, 0.5))
+		if want := 0.2 * (u - 0.5); math.Abs(c.R-want) > 1e-6 {
+			t.Errorf("Sample(%g, 0.5).R = %.4f, want %.4f", u, c.R, want)
+		}
+	}
+	// At the jump from row 0 to row 1 the spline overshoots; it is clamped.
+	for _, v := range []float64{0.6, 0.9, 1.1, 1.4} {
+		if c := tex.Sample(texturePoint(tex, 1.5, v)); c.G < 0 || c.G > 1 {
+			t.Errorf("Sample(1.5, %g).G = %.4f, outside [0, 1]", v, c.G)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	for _, f := range []Filter{FilterNearest, FilterBilinear, FilterBicubic} {
+		got, err := ParseFilter(f.String())
+		if err != nil || got != f {
+			t.Errorf("ParseFilter(%q) = %v, %v", f.String(), got, err)
+		}
+	}
+	if _, err := ParseFilter("trilinear"); err == nil {
+		t.Error("ParseFilter(trilinear): expected an error")
+	}
+}
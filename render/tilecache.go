@@ -0,0 +1,125 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// cacheShards splits a TileCache into independently locked parts, so that
+// workers sampling different tiles rarely wait for each other.
+const cacheShards = 16
+
+// TileCache holds decoded tiles of lazily loaded TIFF textures within a
+// memory budget, evicting the least recently used tiles first. One cache is
+// shared by all textures of a Renderer and is safe for concurrent use.
+type TileCache struct {
+	shards [cacheShards]cacheShard
+
+	hits, misses, evictions atomic.Int64
+	nextSource              atomic.Uint64
+}
+
+type cacheShard struct {
+	mu      sync.Mutex
+	budget  int64
+	used    int64
+	entries map[tileKey]*list.Element
+	lru     list.List // of *cacheEntry, most recently used first
+}
+
+// tileKey identifies tile index of the image registered as source.
+type tileKey struct {
+	source uint64
+	index  int
+}
+
+type cacheEntry struct {
+	key  tileKey
+	data []byte
+}
+
+// CacheStats counts the tile lookups of a TileCache.
+type CacheStats struct {
+	Hits      int64 // tiles found decoded
+	Misses    int64 // tiles read and decoded
+	Evictions int64 // tiles dropped to stay within the budget
+	Bytes     int64 // decoded tile data held now
+	Budget    int64
+}
+
+// HitRate returns the share of lookups that were hits, in [0, 1].
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// NewTileCache returns a cache holding up to budget bytes of decoded tiles.
+// Each part of the cache keeps at least its most recent tile, even if that
+// is over budget.
+func NewTileCache(budget int64) *TileCache {
+	c := &TileCache{}
+	for i := range c.shards {
+		c.shards[i].budget = budget / cacheShards
+		c.shards[i].entries = make(map[tileKey]*list.Element)
+	}
+	return c
+}
+
+// newSource returns a key space for the tiles of one image.
+func (c *TileCache) newSource() uint64 {
+	return c.nextSource.Add(1)
+}
+
+// get returns the tile for key, calling load to decode it on a miss. Two
+// workers missing the same tile at once may both load it.
+func (c *TileCache) get(key tileKey, load func() []byte) []byte {
+	s := &c.shards[(key.source*31+uint64(key.index))%cacheShards]
+
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		s.lru.MoveToFront(e)
+		s.mu.Unlock()
+		c.hits.Add(1)
+		return e.Value.(*cacheEntry).data
+	}
+	s.mu.Unlock()
+	c.misses.Add(1)
+
+	data := load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		s.lru.MoveToFront(e)
+		return e.Value.(*cacheEntry).data
+	}
+	s.entries[key] = s.lru.PushFront(&cacheEntry{key: key, data: data})
+	s.used += int64(len(data))
+	for s.used > s.budget && s.lru.Len() > 1 {
+		old := s.lru.Remove(s.lru.Back()).(*cacheEntry)
+		delete(s.entries, old.key)
+		s.used -= int64(len(old.data))
+		c.evictions.Add(1)
+	}
+	return data
+}
+
+// Stats returns the lookups so far and the memory in use.
+func (c *TileCache) Stats() CacheStats {
+	st := CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		st.Bytes += s.used
+		st.Budget += s.budget
+		s.mu.Unlock()
+	}
+	return st
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/echoflaresat/spacecam/tiffenc"
+)
+
+func TestTileCacheEviction(t *testing.T) {
+	c := NewTileCache(cacheShards * 100) // 100 bytes per shard
+	loads := 0
+	get := func(i int) {
+		c.get(tileKey{1, i}, func() []byte {
+			loads++
+			return make([]byte, 40)
+		})
+	}
+	// Indexes 0, 16 and 32 share a shard, which holds two of them.
+	get(0)
+	get(16)
+	get(0)
+	get(32) // evicts 16, the least recently used
+	get(0)
+	get(16)
+
+	st := c.Stats()
+	if loads != 4 || st.Hits != 2 || st.Misses != 4 || st.Evictions != 2 {
+		t.Errorf("loads = %d, stats = %+v; want 4 loads, 2 hits, 4 misses, 2 evictions", loads, st)
+	}
+	if st.Bytes != 80 {
+		t.Errorf("Bytes = %d, want 80", st.Bytes)
+	}
+}
+
+func TestCachedTIFF(t *testing.T) {
+	const W, H = 600, 300
+	src := image.NewNRGBA(image.Rect(0, 0, W, H))
+	for y := 0; y < H; y++ {
+		for x := 0; x < W; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "tex.tif")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw, err := tiffenc.NewTiled(f, W, H, tiffenc.DefaultTileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	across, down := tw.Tiles()
+	for row := 0; row < down; row++ {
+		for col := 0; col < across; col++ {
+			if err := tw.WriteTile(col, row, src); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// A budget of one tile per shard, read from several workers at once.
+	cache := NewTileCache(cacheShards * tiffenc.DefaultTileSize * tiffenc.DefaultTileSize * 3)
+	tex, err := LoadTextureCached(path, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Close()
+	if _, ok := tex.img.(*cachedTIFF); !ok {
+		t.Fatalf("texture image is %T, want *cachedTIFF", tex.img)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := w; y < H; y += 4 {
+				for x := 0; x < W; x++ {
+					r, g, b, _ := tex.img.At(x, y).RGBA()
+					want := src.NRGBAAt(x, y)
+					if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+						errs <- fmt.Errorf("pixel (%d, %d) = %d,%d,%d, want %v", x, y, r>>8, g>>8, b>>8, want)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	st := cache.Stats()
+	if st.Misses < int64(across*down) || st.Hits == 0 {
+		t.Errorf("stats = %+v, want at least %d misses and some hits", st, across*down)
+	}
+}
+
+// writeTestTIFF writes a 16×16 TIFF of a single tile holding data, with the
+// given photometric interpretation, samples and bits per sample, compression
+// and predictor.
+func writeTestTIFF(t *testing.T, photometric, samples, bits, compression, predictor int, data []byte) string {
+	const size = 16
+	le := binary.LittleEndian
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'I', 42, 0, 0, 0, 0, 0})
+	tileAt := buf.Len()
+	buf.Write(data)
+	bitsAt := uint32(bits) // fits in the entry for one sample
+	if samples > 1 {
+		bitsAt = uint32(buf.Len())
+		for i := 0; i < samples; i++ {
+			binary.Write(&buf, le, uint16(bits))
+		}
+	}
+	if buf.Len()%2 != 0 {
+		buf.WriteByte(0)
+	}
+
+	type entry struct{ tag, typ, count, value uint32 }
+	entries := []entry{
+		{tiffImageWidth, tiffShort, 1, size},
+		{tiffImageLength, tiffShort, 1, size},
+		{tiffBitsPerSample, tiffShort, uint32(samples), bitsAt},
+		{tiffCompression, tiffShort, 1, uint32(compression)},
+		{tiffPhotometricInterpretation, tiffShort, 1, uint32(photometric)},
+		{tiffSamplesPerPixel, tiffShort, 1, uint32(samples)},
+		{tiffPlanarConfiguration, tiffShort, 1, 1},
+		{tiffPredictor, tiffShort, 1, uint32(predictor)},
+		{tiffTileWidth, tiffShort, 1, size},
+		{tiffTileLength, tiffShort, 1, size},
+		{tiffTileOffsets, tiffLong, 1, uint32(tileAt)},
+		{tiffTileByteCounts, tiffLong, 1, uint32(len(data))},
+	}
+	ifd := buf.Len()
+	binary.Write(&buf, le, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&buf, le, uint16(e.tag))
+		binary.Write(&buf, le, uint16(e.typ))
+		binary.Write(&buf, le, e.count)
+		if e.typ == tiffShort && e.count == 1 {
+			binary.Write(&buf, le, [2]uint16{uint16(e.value)})
+		} else {
+			binary.Write(&buf, le, e.value)
+		}
+	}
+	binary.Write(&buf, le, uint32(0))
+	b := buf.Bytes()
+	le.PutUint32(b[4:], uint32(ifd))
+
+	path := filepath.Join(t.TempDir(), "tex.tif")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCachedTIFFFallback(t *testing.T) {
+	// 16-bit samples, all 0x8080, are left to tiff.Decode.
+	path := writeTestTIFF(t, 2, 3, 16, 1, 1, bytes.Repeat([]byte{0x80}, 16*16*3*2))
+	tex, err := LoadTextureCached(path, NewTileCache(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Close()
+	if _, ok := tex.img.(*cachedTIFF); ok {
+		t.Fatal("16-bit TIFF read by cachedTIFF, want the tiff.Decode fallback")
+	}
+	if r, _, _, _ := tex.img.At(3, 5).RGBA(); r != 0x8080 {
+		t.Errorf("pixel red = %#x, want 0x8080", r)
+	}
+}
+
+func TestCachedTIFFWhiteIsZero(t *testing.T) {
+	// Gray 0x30 stored WhiteIsZero is 0xcf in BlackIsZero terms.
+	path := writeTestTIFF(t, 0, 1, 8, 1, 1, bytes.Repeat([]byte{0x30}, 16*16))
+	if _, err := openCachedTIFF(mustOpen(t, path), NewTileCache(1<<20)); err != errNotTiled {
+		t.Fatalf("openCachedTIFF = %v, want errNotTiled", err)
+	}
+	tex, err := LoadTextureCached(path, NewTileCache(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Close()
+	if r, _, _, _ := tex.img.At(3, 5).RGBA(); r>>8 != 0xcf {
+		t.Errorf("pixel = %#x, want 0xcf", r>>8)
+	}
+
+	// BlackIsZero gray is read through the cache as it is.
+	path = writeTestTIFF(t, 1, 1, 8, 1, 1, bytes.Repeat([]byte{0x30}, 16*16))
+	img, err := openCachedTIFF(mustOpen(t, path), NewTileCache(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := img.At(3, 5).RGBA(); r>>8 != 0x30 {
+		t.Errorf("BlackIsZero pixel = %#x, want 0x30", r>>8)
+	}
+}
+
+func TestCachedTIFFPredictor(t *testing.T) {
+	// Horizontal differencing stores each sample as the difference from the
+	// one to its left, which cachedTIFF does not undo, so it leaves the file
+	// to tiff.Decode.
+	var raw, zipped bytes.Buffer
+	for y := 0; y < 16; y++ {
+		raw.Write([]byte{10, 20, 30})
+		raw.Write(bytes.Repeat([]byte{1, 1, 1}, 15))
+	}
+	zw := zlib.NewWriter(&zipped)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	path := writeTestTIFF(t, 2, 3, 8, 8, 2, zipped.Bytes())
+	if _, err := openCachedTIFF(mustOpen(t, path), NewTileCache(1<<20)); err != errNotTiled {
+		t.Fatalf("openCachedTIFF = %v, want errNotTiled", err)
+	}
+	tex, err := LoadTextureCached(path, NewTileCache(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Close()
+	if _, ok := tex.img.(*cachedTIFF); ok {
+		t.Error("TIFF with a predictor read by cachedTIFF, want the tiff.Decode fallback")
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// cachedTIFF is a tiled TIFF whose tiles are read on demand through a
+// TileCache. It handles the layout of the texture pipeline: 8-bit RGB or
+// BlackIsZero gray, chunky, uncompressed or DEFLATE.
+type cachedTIFF struct {
+	r              io.ReaderAt
+	cache          *TileCache
+	source         uint64
+	width, height  int
+	tileW, tileH   int
+	across         int
+	samples        int
+	deflate        bool
+	offsets, sizes []uint64
+}
+
+var errNotTiled = errors.New("not a tiled TIFF")
+
+// TIFF tags and values read by openCachedTIFF.
+const (
+	tiffImageWidth                = 256
+	tiffImageLength               = 257
+	tiffBitsPerSample             = 258
+	tiffCompression               = 259
+	tiffPhotometricInterpretation = 262
+	tiffSamplesPerPixel           = 277
+	tiffPlanarConfiguration       = 284
+	tiffPredictor                 = 317
+	tiffTileWidth                 = 322
+	tiffTileLength                = 323
+	tiffTileOffsets               = 324
+	tiffTileByteCounts            = 325
+
+	tiffShort = 3
+	tiffLong  = 4
+)
+
+// openCachedTIFF reads the directory of the tiled TIFF in r. It returns
+// errNotTiled for files it can't read lazily, such as striped TIFFs or other
+// layouts, compressions or predictors, which are left to tiff.Decode.
+func openCachedTIFF(r io.ReaderAt, cache *TileCache) (*cachedTIFF, error) {
+	var head [8]byte
+	if _, err := r.ReadAt(head[:], 0); err != nil {
+		return nil, errNotTiled
+	}
+	var bo binary.ByteOrder
+	switch string(head[:4]) {
+	case "II\x2A\x00":
+		bo = binary.LittleEndian
+	case "MM\x00\x2A":
+		bo = binary.BigEndian
+	default:
+		return nil, errNotTiled
+	}
+
+	ifd := int64(bo.Uint32(head[4:]))
+	var n [2]byte
+	if _, err := r.ReadAt(n[:], ifd); err != nil {
+		return nil, err
+	}
+	entries := make([]byte, 12*int(bo.Uint16(n[:])))
+	if _, err := r.ReadAt(entries, ifd+2); err != nil {
+		return nil, err
+	}
+
+	t := &cachedTIFF{r: r, cache: cache, samples: 1}
+	bits, compression, photometric, planar, predictor := uint64(0), uint64(1), uint64(0), uint64(1), uint64(1)
+	for e := entries; len(e) >= 12; e = e[12:] {
+		values, err := tiffValues(r, bo, e)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		switch bo.Uint16(e) {
+		case tiffImageWidth:
+			t.width = int(values[0])
+		case tiffImageLength:
+			t.height = int(values[0])
+		case tiffBitsPerSample:
+			bits = values[0]
+		case tiffCompression:
+			compression = values[0]
+		case tiffPhotometricInterpretation:
+			photometric = values[0]
+		case tiffSamplesPerPixel:
+			t.samples = int(values[0])
+		case tiffPlanarConfiguration:
+			planar = values[0]
+		case tiffPredictor:
+			predictor = values[0]
+		case tiffTileWidth:
+			t.tileW = int(values[0])
+		case tiffTileLength:
+			t.tileH = int(values[0])
+		case tiffTileOffsets:
+			t.offsets = values
+		case tiffTileByteCounts:
+			t.sizes = values
+		}
+	}
+
+	if t.tileW <= 0 || t.tileH <= 0 {
+		return nil, errNotTiled
+	}
+	if bits != 8 || planar != 1 || predictor != 1 ||
+		!(photometric == 2 && t.samples == 3 || photometric == 1 && t.samples == 1) {
+		return nil, errNotTiled
+	}
+	switch compression {
+	case 1:
+	case 8, 32946: // Adobe and old-style DEFLATE
+		t.deflate = true
+	default:
+		return nil, errNotTiled
+	}
+	t.across = (t.width + t.tileW - 1) / t.tileW
+	down := (t.height + t.tileH - 1) / t.tileH
+	if len(t.offsets) != t.across*down || len(t.sizes) != len(t.offsets) {
+		return nil, fmt.Errorf("TIFF has %d tile offsets and %d sizes for %d tiles", len(t.offsets), len(t.sizes), t.across*down)
+	}
+	t.source = cache.newSource()
+	return t, nil
+}
+
+// tiffValues returns the SHORT or LONG values of the directory entry e,
+// reading them from r when they don't fit in the entry. Other types yield
+// none.
+func tiffValues(r io.ReaderAt, bo binary.ByteOrder, e []byte) ([]uint64, error) {
+	typ, count := bo.Uint16(e[2:]), int(bo.Uint32(e[4:]))
+	var size int
+	switch typ {
+	case tiffShort:
+		size = 2
+	case tiffLong:
+		size = 4
+	default:
+		return nil, nil
+	}
+	data := e[8:12]
+	if count*size > 4 {
+		data = make([]byte, count*size)
+		if _, err := r.ReadAt(data, int64(bo.Uint32(e[8:]))); err != nil {
+			return nil, err
+		}
+	}
+	values := make([]uint64, count)
+	for i := range values {
+		if size == 2 {
+			values[i] = uint64(bo.Uint16(data[2*i:]))
+		} else {
+			values[i] = uint64(bo.Uint32(data[4*i:]))
+		}
+	}
+	return values, nil
+}
+
+func (t *cachedTIFF) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (t *cachedTIFF) Bounds() image.Rectangle {
+	return image.Rect(0, 0, t.width, t.height)
+}
+
+func (t *cachedTIFF) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(t.Bounds())) {
+		return color.RGBA{}
+	}
+	index := y/t.tileH*t.across + x/t.tileW
+	tile := t.cache.get(tileKey{t.source, index}, func() []byte { return t.loadTile(index) })
+
+	o := ((y%t.tileH)*t.tileW + x%t.tileW) * t.samples
+	if t.samples == 1 {
+		return color.RGBA{tile[o], tile[o], tile[o], 255}
+	}
+	return color.RGBA{tile[o], tile[o+1], tile[o+2], 255}
+}
+
+// loadTile reads and decompresses tile index. Like the other lazy image
+// readers it panics on I/O errors, which image.Image gives no way to return.
+func (t *cachedTIFF) loadTile(index int) []byte {
+	raw := make([]byte, t.sizes[index])
+	if _, err := t.r.ReadAt(raw, int64(t.offsets[index])); err != nil && err != io.EOF {
+		panic(fmt.Sprintf("reading TIFF tile %d: %v", index, err))
+	}
+	tile := raw
+	if t.deflate {
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			panic(fmt.Sprintf("decompressing TIFF tile %d: %v", index, err))
+		}
+		if tile, err = io.ReadAll(zr); err != nil {
+			panic(fmt.Sprintf("decompressing TIFF tile %d: %v", index, err))
+		}
+	}
+	if want := t.tileW * t.tileH * t.samples; len(tile) < want {
+		panic(fmt.Sprintf("TIFF tile %d has %d bytes, want %d", index, len(tile), want))
+	}
+	return tile
+}
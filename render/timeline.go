@@ -0,0 +1,147 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/echoflaresat/spacecam/colors"
+	"github.com/echoflaresat/spacecam/vectors"
+)
+
+// TimedPath names a texture valid at a moment, e.g. one cloud composite of a
+// sequence.
+type TimedPath struct {
+	Time time.Time
+	Path string
+}
+
+// TimedFrame is one texture of a TimedTexture.
+type TimedFrame struct {
+	Time    time.Time
+	Texture TextureSampler
+}
+
+// TimedTexture is a texture known at a sequence of moments, like global cloud
+// composites received every few hours. Between two frames it cross-fades;
+// before the first and after the last it holds them. With Motion set, the
+// cross-fade follows the motion estimated between the frames, so that clouds
+// drift from one position to the next instead of fading out in one place and
+// in at another.
+type TimedTexture struct {
+	Frames []TimedFrame // in time order
+	Motion bool
+
+	mu    sync.Mutex
+	flows map[int]*flowField // by index of the earlier frame
+}
+
+// LoadTimedTexture loads the textures of frames with load, sorted by time.
+func LoadTimedTexture(frames []TimedPath, motion bool, load func(path string) (Texture, error)) (*TimedTexture, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("no texture frames")
+	}
+	frames = slices.Clone(frames)
+	slices.SortStableFunc(frames, func(a, b TimedPath) int { return a.Time.Compare(b.Time) })
+
+	t := &TimedTexture{Motion: motion}
+	for _, f := range frames {
+		tex, err := load(f.Path)
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("texture frame at %s: %w", f.Time.Format(time.RFC3339), err)
+		}
+		t.Frames = append(t.Frames, TimedFrame{Time: f.Time, Texture: tex})
+	}
+	return t, nil
+}
+
+// AtTime returns the blend of the two frames around when.
+func (t *TimedTexture) AtTime(when time.Time) TextureSampler {
+	i := sort.Search(len(t.Frames), func(i int) bool { return t.Frames[i].Time.After(when) })
+	if i == 0 {
+		return t.Frames[0].Texture
+	}
+	if i == len(t.Frames) {
+		return t.Frames[i-1].Texture
+	}
+
+	a, b := t.Frames[i-1], t.Frames[i]
+	f := float64(when.Sub(a.Time)) / float64(b.Time.Sub(a.Time))
+	if f == 0 {
+		return a.Texture
+	}
+	if !t.Motion {
+		return blendSampler{a.Texture, b.Texture, f}
+	}
+	return motionSampler{a.Texture, b.Texture, f, t.flow(i - 1)}
+}
+
+// flow returns the motion from frame i to frame i+1, estimating it the
+// first time it is needed.
+func (t *TimedTexture) flow(i int) *flowField {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.flows == nil {
+		t.flows = make(map[int]*flowField)
+	}
+	if _, ok := t.flows[i]; !ok {
+		t.flows[i] = estimateFlow(t.Frames[i].Texture, t.Frames[i+1].Texture)
+	}
+	return t.flows[i]
+}
+
+// Sample samples the first frame; Render samples AtTime(Options.Time)
+// instead.
+func (t *TimedTexture) Sample(P vectors.Vec3) colors.Color4 {
+	return t.Frames[0].Texture.Sample(P)
+}
+
+// SampleLatLon samples the first frame, like Sample.
+func (t *TimedTexture) SampleLatLon(lat, lon float64) colors.Color4 {
+	return t.Frames[0].Texture.SampleLatLon(lat, lon)
+}
+
+// Close closes the textures of all frames.
+func (t *TimedTexture) Close() error {
+	var errs []error
+	for _, f := range t.Frames {
+		errs = append(errs, closeSampler(f.Texture))
+	}
+	return errors.Join(errs...)
+}
+
+// motionSampler blends a and b like blendSampler, but samples each displaced
+// along the motion between them: content that moves by v from a to b is,
+// the fraction f of the way, at a's position plus f·v.
+type motionSampler struct {
+	a, b TextureSampler
+	f    float64
+	flow *flowField
+}
+
+// points returns where to sample a and b for the direction P.
+func (s motionSampler) points(P vectors.Vec3) (vectors.Vec3, vectors.Vec3) {
+	lat, lon := latLon(P)
+	dLat, dLon := s.flow.at(lat, lon)
+	pa := unitVector(lat-s.f*dLat, lon-s.f*dLon)
+	pb := unitVector(lat+(1-s.f)*dLat, lon+(1-s.f)*dLon)
+	return pa, pb
+}
+
+func (s motionSampler) Sample(P vectors.Vec3) colors.Color4 {
+	pa, pb := s.points(P)
+	return s.a.Sample(pa).Mix(s.b.Sample(pb), s.f)
+}
+
+func (s motionSampler) SampleLatLon(lat, lon float64) colors.Color4 {
+	return s.Sample(unitVector(lat, lon))
+}
+
+func (s motionSampler) SampleFootprint(P vectors.Vec3, footprint float64) colors.Color4 {
+	pa, pb := s.points(P)
+	return sampleSurface(s.a, pa, footprint).Mix(sampleSurface(s.b, pb, footprint), s.f)
+}
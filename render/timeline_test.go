@@ -0,0 +1,80 @@
+package render
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/colors"
+)
+
+// blobSampler returns a sampler with a round white blob of 3° radius at
+// (lat0, lon0) on black.
+func blobSampler(lat0, lon0 float64) SamplerFunc {
+	return func(lat, lon float64) colors.Color4 {
+		d2 := (lat-lat0)*(lat-lat0) + (lon-lon0)*(lon-lon0)
+		v := math.Exp(-d2 / (2 * 3 * 3))
+		return colors.New(v, v, v, 1)
+	}
+}
+
+func TestTimedTextureAtTime(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC)
+	tex, err := LoadTimedTexture([]TimedPath{
+		{t0.Add(6 * time.Hour), "b"},
+		{t0, "a"},
+	}, false, func(path string) (Texture, error) {
+		if path == "a" {
+			return monthTexture(0), nil
+		}
+		return monthTexture(12), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t    time.Time
+		want float64
+	}{
+		{t0.Add(-time.Hour), 0}, // before the first frame
+		{t0, 0},                 // at the first frame
+		{t0.Add(90 * time.Minute), 0.25},
+		{t0.Add(6 * time.Hour), 1}, // at the last frame
+		{t0.Add(24 * time.Hour), 1},
+	}
+	for _, tt := range tests {
+		c := tex.AtTime(tt.t).SampleLatLon(0, 0)
+		if math.Abs(c.R-tt.want) > 1e-9 {
+			t.Errorf("at %v: R = %.4f, want %.4f", tt.t, c.R, tt.want)
+		}
+	}
+}
+
+func TestTimedTextureMotion(t *testing.T) {
+	t0 := time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC)
+	tex := &TimedTexture{Motion: true, Frames: []TimedFrame{
+		{t0, blobSampler(10, 20)},
+		{t0.Add(time.Hour), blobSampler(10, 23)}, // 3° east an hour later
+	}}
+
+	dLat, dLon := tex.flow(0).at(10, 20)
+	if math.Abs(dLat) > 0.1 || math.Abs(dLon-3) > 0.1 {
+		t.Errorf("flow at the blob = %.2f, %.2f, want 0, 3", dLat, dLon)
+	}
+	dLat, dLon = tex.flow(0).at(-40, 100)
+	if dLat != 0 || dLon != 0 {
+		t.Errorf("flow on clear sky = %.2f, %.2f, want 0, 0", dLat, dLon)
+	}
+
+	// Halfway through, the blob is whole halfway along, where a cross-fade
+	// would show two dimmer blobs.
+	mid := tex.AtTime(t0.Add(30*time.Minute)).SampleLatLon(10, 21.5)
+	if mid.R < 0.99 {
+		t.Errorf("motion blend at the midpoint = %.3f, want 1", mid.R)
+	}
+	tex.Motion = false
+	if fade := tex.AtTime(t0.Add(30*time.Minute)).SampleLatLon(10, 21.5); fade.R > 0.9 {
+		t.Errorf("cross-fade at the midpoint = %.3f, want below 0.9", fade.R)
+	}
+}
@@ -27,6 +27,7 @@ func TestViews(t *testing.T) {
 	fov := 60.0
 	tilt := 0.0
 	yaw := 0.0
+	roll := 0.0
 	size := 640
 	supersample := 3
 	renderTime, err := time.Parse(time.RFC3339, "2024-08-08T09:23:00Z")
@@ -60,12 +61,13 @@ func TestViews(t *testing.T) {
 
 					numWorkers := runtime.GOMAXPROCS(0)
 					sunDir := earth.SunDirectionECEF(renderTime)
-					camera := render.NewCamera(c.lat, c.lon, c.alt, fov, tilt, yaw)
+					camera := render.NewCamera(c.lat, c.lon, c.alt, fov, tilt, yaw, roll)
 
 					return render.RenderScene(
 						camera,
 						sunDir,
 						size,
+						size,
 						supersample,
 						theme,
 						numWorkers,
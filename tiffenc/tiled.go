@@ -0,0 +1,271 @@
+// Package tiffenc writes tiled, DEFLATE-compressed RGB TIFF files one tile at
+// a time, so that images far larger than memory can be produced. The layout
+// (8-bit RGB, square tiles, zlib "Adobe Deflate" compression, a single IFD)
+// is the one the renderer's lazy TIFF texture loader reads tile by tile.
+package tiffenc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// DefaultTileSize is the tile edge length used by NASA-style texture
+// pipelines (gdal_merge.py -co BLOCKXSIZE=256 -co BLOCKYSIZE=256).
+const DefaultTileSize = 256
+
+// TIFF tags, field types and values written by Tiled.
+const (
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagSamplesPerPixel           = 277
+	tagPlanarConfiguration       = 284
+	tagTileWidth                 = 322
+	tagTileLength                = 323
+	tagTileOffsets               = 324
+	tagTileByteCounts            = 325
+
+	typeShort = 3
+	typeLong  = 4
+
+	compressionDeflate = 8
+	photometricRGB     = 2
+	planarContig       = 1
+)
+
+// ErrTooLarge is returned when the file would outgrow the 4 GiB offsets of
+// classic TIFF.
+var ErrTooLarge = errors.New("tiffenc: output exceeds the 4 GiB limit of classic TIFF")
+
+// Tiled writes a tiled TIFF. Tiles may be written in any order, each exactly
+// once, and only one tile's pixels are held at a time. Close writes the
+// directory that indexes them.
+type Tiled struct {
+	w             io.WriteSeeker
+	width, height int
+	tileSize      int
+	across, down  int
+	offsets       []uint32
+	byteCounts    []uint32
+	pos           int64 // end of the data written so far
+	buf           []byte
+	compressed    bytes.Buffer
+	zw            *zlib.Writer
+	closed        bool
+}
+
+// NewTiled starts a width×height TIFF with tileSize×tileSize tiles on w.
+// tileSize must be a positive multiple of 16, as TIFF requires.
+func NewTiled(w io.WriteSeeker, width, height, tileSize int) (*Tiled, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("tiffenc: invalid size %dx%d", width, height)
+	}
+	if tileSize <= 0 || tileSize%16 != 0 {
+		return nil, fmt.Errorf("tiffenc: tile size %d is not a positive multiple of 16", tileSize)
+	}
+
+	t := &Tiled{
+		w:        w,
+		width:    width,
+		height:   height,
+		tileSize: tileSize,
+		across:   (width + tileSize - 1) / tileSize,
+		down:     (height + tileSize - 1) / tileSize,
+		buf:      make([]byte, tileSize*tileSize*3),
+	}
+	t.offsets = make([]uint32, t.across*t.down)
+	t.byteCounts = make([]uint32, t.across*t.down)
+	t.zw, _ = zlib.NewWriterLevel(&t.compressed, zlib.DefaultCompression)
+
+	// Header; the IFD offset is filled in by Close.
+	header := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	t.pos = int64(len(header))
+	return t, nil
+}
+
+// Tiles returns the number of tile columns and rows.
+func (t *Tiled) Tiles() (across, down int) {
+	return t.across, t.down
+}
+
+// TileBounds returns the image rectangle covered by tile (col, row), clipped
+// to the image at the right and bottom edges.
+func (t *Tiled) TileBounds(col, row int) image.Rectangle {
+	x, y := col*t.tileSize, row*t.tileSize
+	return image.Rect(x, y, x+t.tileSize, y+t.tileSize).Intersect(image.Rect(0, 0, t.width, t.height))
+}
+
+// WriteTile compresses and writes tile (col, row). src must cover
+// TileBounds(col, row), in image coordinates; pixels outside it are ignored,
+// and the padding of edge tiles is written black. Alpha is composited over
+// black.
+func (t *Tiled) WriteTile(col, row int, src image.Image) error {
+	if t.closed {
+		return errors.New("tiffenc: write after Close")
+	}
+	if col < 0 || col >= t.across || row < 0 || row >= t.down {
+		return fmt.Errorf("tiffenc: tile (%d, %d) outside the %dx%d tile grid", col, row, t.across, t.down)
+	}
+	i := row*t.across + col
+	if t.byteCounts[i] != 0 {
+		return fmt.Errorf("tiffenc: tile (%d, %d) written twice", col, row)
+	}
+	r := t.TileBounds(col, row)
+	if !r.In(src.Bounds()) {
+		return fmt.Errorf("tiffenc: tile (%d, %d) needs %v, image has %v", col, row, r, src.Bounds())
+	}
+
+	t.fill(r, src)
+
+	t.compressed.Reset()
+	t.zw.Reset(&t.compressed)
+	if _, err := t.zw.Write(t.buf); err != nil {
+		return err
+	}
+	if err := t.zw.Close(); err != nil {
+		return err
+	}
+
+	n := int64(t.compressed.Len())
+	if t.pos+n > math.MaxUint32 {
+		return ErrTooLarge
+	}
+	if _, err := t.w.Write(t.compressed.Bytes()); err != nil {
+		return err
+	}
+	t.offsets[i] = uint32(t.pos)
+	t.byteCounts[i] = uint32(n)
+	t.pos += n
+	return nil
+}
+
+// fill copies the pixels of r from src into the tile buffer as packed RGB.
+func (t *Tiled) fill(r image.Rectangle, src image.Image) {
+	clear(t.buf)
+	stride := t.tileSize * 3
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := t.buf[(y-r.Min.Y)*stride:]
+		switch img := src.(type) {
+		case *image.NRGBA:
+			pix := img.Pix[img.PixOffset(r.Min.X, y):]
+			for x := 0; x < r.Dx(); x++ {
+				p := pix[x*4 : x*4+4]
+				if p[3] == 255 {
+					copy(row[x*3:x*3+3], p[:3])
+				} else {
+					c := color.RGBAModel.Convert(color.NRGBA{p[0], p[1], p[2], p[3]}).(color.RGBA)
+					row[x*3], row[x*3+1], row[x*3+2] = c.R, c.G, c.B
+				}
+			}
+		case *image.RGBA:
+			pix := img.Pix[img.PixOffset(r.Min.X, y):]
+			for x := 0; x < r.Dx(); x++ {
+				copy(row[x*3:x*3+3], pix[x*4:x*4+3])
+			}
+		default:
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)
+				o := (x - r.Min.X) * 3
+				row[o], row[o+1], row[o+2] = c.R, c.G, c.B
+			}
+		}
+	}
+}
+
+// Close writes the image directory and points the header at it. It fails if
+// any tile has not been written. It does not close the underlying writer.
+func (t *Tiled) Close() error {
+	if t.closed {
+		return nil
+	}
+	for i, n := range t.byteCounts {
+		if n == 0 {
+			return fmt.Errorf("tiffenc: tile (%d, %d) was never written", i%t.across, i/t.across)
+		}
+	}
+	t.closed = true
+
+	le := binary.LittleEndian
+	n := len(t.offsets)
+
+	// Out-of-line values go first, word aligned, then the IFD.
+	var extra bytes.Buffer
+	if t.pos%2 == 1 {
+		extra.WriteByte(0)
+	}
+	bitsAt := t.pos + int64(extra.Len())
+	for i := 0; i < 3; i++ {
+		binary.Write(&extra, le, uint16(8))
+	}
+	offsetsAt := bitsAt + 6
+	binary.Write(&extra, le, t.offsets)
+	countsAt := offsetsAt + int64(4*n)
+	binary.Write(&extra, le, t.byteCounts)
+	ifdAt := countsAt + int64(4*n)
+
+	type entry struct {
+		tag, typ     uint16
+		count, value uint32
+	}
+	entries := []entry{
+		{tagImageWidth, typeLong, 1, uint32(t.width)},
+		{tagImageLength, typeLong, 1, uint32(t.height)},
+		{tagBitsPerSample, typeShort, 3, uint32(bitsAt)},
+		{tagCompression, typeShort, 1, compressionDeflate},
+		{tagPhotometricInterpretation, typeShort, 1, photometricRGB},
+		{tagSamplesPerPixel, typeShort, 1, 3},
+		{tagPlanarConfiguration, typeShort, 1, planarContig},
+		{tagTileWidth, typeLong, 1, uint32(t.tileSize)},
+		{tagTileLength, typeLong, 1, uint32(t.tileSize)},
+		{tagTileOffsets, typeLong, uint32(n), uint32(offsetsAt)},
+		{tagTileByteCounts, typeLong, uint32(n), uint32(countsAt)},
+	}
+	if n == 1 {
+		// A single value is stored in the entry itself.
+		entries[9].value, entries[10].value = t.offsets[0], t.byteCounts[0]
+	}
+
+	binary.Write(&extra, le, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&extra, le, e.tag)
+		binary.Write(&extra, le, e.typ)
+		binary.Write(&extra, le, e.count)
+		if e.typ == typeShort && e.count == 1 {
+			binary.Write(&extra, le, uint16(e.value))
+			binary.Write(&extra, le, uint16(0))
+		} else {
+			binary.Write(&extra, le, e.value)
+		}
+	}
+	binary.Write(&extra, le, uint32(0)) // no next IFD
+
+	if t.pos+int64(extra.Len()) > math.MaxUint32 {
+		return ErrTooLarge
+	}
+	if _, err := t.w.Write(extra.Bytes()); err != nil {
+		return err
+	}
+
+	var ifd [4]byte
+	le.PutUint32(ifd[:], uint32(ifdAt))
+	if _, err := t.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := t.w.Write(ifd[:]); err != nil {
+		return err
+	}
+	_, err := t.w.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,94 @@
+package tiffenc
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/echoflaresat/tiff"
+)
+
+func TestTiledRoundTrip(t *testing.T) {
+	const W, H = 600, 300 // partial tiles at the right and bottom
+	src := image.NewNRGBA(image.Rect(0, 0, W, H))
+	for y := 0; y < H; y++ {
+		for x := 0; x < W; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "out.tif")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw, err := NewTiled(f, W, H, DefaultTileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	across, down := tw.Tiles()
+	if across != 3 || down != 2 {
+		t.Fatalf("Tiles() = %d, %d, want 3, 2", across, down)
+	}
+	// Out of order, and each tile from its own sub-image.
+	for row := down - 1; row >= 0; row-- {
+		for col := 0; col < across; col++ {
+			if err := tw.WriteTile(col, row, src.SubImage(tw.TileBounds(col, row))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := tiff.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != src.Bounds() {
+		t.Fatalf("bounds = %v, want %v", img.Bounds(), src.Bounds())
+	}
+	for y := 0; y < H; y++ {
+		for x := 0; x < W; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			want := src.NRGBAAt(x, y)
+			if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+				t.Fatalf("pixel (%d, %d) = %d,%d,%d, want %v", x, y, r>>8, g>>8, b>>8, want)
+			}
+		}
+	}
+}
+
+func TestTiledMissingTile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.tif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw, err := NewTiled(f, 300, 100, DefaultTileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	if err := tw.WriteTile(0, 0, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteTile(0, 0, src); err == nil {
+		t.Error("expected an error writing a tile twice")
+	}
+	if err := tw.Close(); err == nil {
+		t.Error("expected an error closing with a tile missing")
+	}
+}
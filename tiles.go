@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/echoflaresat/spacecam/earth"
+	"github.com/echoflaresat/spacecam/render"
+	"github.com/echoflaresat/spacecam/tiffenc"
+)
+
+// A tiled render splits one huge frame into tiles rendered by any number of
+// processes, possibly on different machines sharing the output directory:
+//
+//	dir/manifest.json    the frame, its tiles and which of them are done
+//	dir/manifest.lock    held briefly while a process updates the manifest
+//	dir/tile_R_C.png     a finished tile
+//	dir/tile_R_C.claim   a tile being rendered, touched while work goes on
+//
+// A process claims a free tile by creating its claim file, which fails if
+// another process got there first. A claim that hasn't been touched for
+// staleClaim is taken to belong to a process that died, and is taken over.
+const (
+	manifestName   = "manifest.json"
+	claimHeartbeat = 30 * time.Second
+	staleClaim     = 5 * time.Minute
+	staleLock      = 30 * time.Second
+)
+
+// tileManifest describes a tiled render.
+type tileManifest struct {
+	Width    int         `json:"width"`
+	Height   int         `json:"height"`
+	TileSize int         `json:"tileSize"`
+	Scene    []string    `json:"scene"` // the flags that define the image
+	Tiles    []tileEntry `json:"tiles"`
+}
+
+type tileEntry struct {
+	Col  int    `json:"col"`
+	Row  int    `json:"row"`
+	File string `json:"file"`
+	Done bool   `json:"done"`
+}
+
+// localFlags don't change the rendered pixels, so processes sharing a tiled
+// render may differ in them.
+var localFlags = []string{"render-tiles", "tile-size", "assemble", "panoramic", "out", "quiet", "timeout", "h"}
+
+// sceneFlags returns the explicitly set flags that define the image, sorted.
+func sceneFlags() []string {
+	var scene []string
+	flag.Visit(func(f *flag.Flag) {
+		if !slices.Contains(localFlags, f.Name) {
+			scene = append(scene, f.Name+"="+f.Value.String())
+		}
+	})
+	slices.Sort(scene)
+	return scene
+}
+
+func (m *tileManifest) rect(e tileEntry) image.Rectangle {
+	x, y := e.Col*m.TileSize, e.Row*m.TileSize
+	return image.Rect(x, y, x+m.TileSize, y+m.TileSize).Intersect(image.Rect(0, 0, m.Width, m.Height))
+}
+
+// renderTiles renders free tiles of the -render-tiles frame until none are
+// left, creating the manifest if this is the first process to start.
+func renderTiles(ctx context.Context, cfg config, renderTime time.Time, renderer *render.Renderer) error {
+	if !isFlagSet("time") {
+		log.Fatalf("-render-tiles needs -time, so that every process renders the same moment")
+	}
+	if *cfg.cubemap != "" || *cfg.crop != "" {
+		log.Fatalf("-render-tiles renders a single view; it can't be combined with -cubemap or -crop")
+	}
+	size := *cfg.tileSize
+	if size <= 0 || size%tiffenc.DefaultTileSize != 0 {
+		log.Fatalf("Invalid -tile-size %d: must be a positive multiple of %d", size, tiffenc.DefaultTileSize)
+	}
+
+	dir := *cfg.renderTiles
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	width, height := outputSize(cfg)
+	m, err := openManifest(dir, width, height, size)
+	if err != nil {
+		return err
+	}
+
+	camera := newCamera(cfg, *cfg.lat, *cfg.lon, *cfg.alt, renderTime)
+
+	rendered := 0
+	for {
+		e, release, err := claimTile(dir, m)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			break // every tile is done or being rendered elsewhere
+		}
+
+		err = renderTile(ctx, cfg, dir, m, e, camera, renderTime, renderer)
+		if err == nil {
+			err = markDone(dir, e)
+		}
+		release()
+		if err != nil {
+			return fmt.Errorf("tile %d,%d: %w", e.Col, e.Row, err)
+		}
+		rendered++
+
+		if m, err = readManifest(dir); err != nil {
+			return err
+		}
+	}
+
+	if *cfg.quiet {
+		return nil
+	}
+	done := 0
+	for _, e := range m.Tiles {
+		if e.Done {
+			done++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Rendered %d tiles; %d of %d done\n", rendered, done, len(m.Tiles))
+	return nil
+}
+
+func renderTile(ctx context.Context, cfg config, dir string, m *tileManifest, e tileEntry, camera render.Camera, renderTime time.Time, renderer *render.Renderer) error {
+	img, err := renderer.Render(ctx, camera, earth.SunDirectionECEF(renderTime), render.Options{
+		Width:         m.Width,
+		Height:        m.Height,
+		Supersampling: *cfg.supersample,
+		Progress:      newProgress(cfg, fmt.Sprintf("tile %d,%d", e.Col, e.Row)),
+		Crop:          m.rect(e),
+		Time:          renderTime,
+	})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, e.File)
+	if err := writePNG(path+".tmp", img); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// openManifest reads the manifest in dir, or creates it for a width×height
+// frame in size×size tiles. An existing manifest must describe the same
+// frame and scene.
+func openManifest(dir string, width, height, size int) (*tileManifest, error) {
+	want := &tileManifest{Width: width, Height: height, TileSize: size, Scene: sceneFlags()}
+	for row := 0; row*size < height; row++ {
+		for col := 0; col*size < width; col++ {
+			want.Tiles = append(want.Tiles, tileEntry{
+				Col:  col,
+				Row:  row,
+				File: fmt.Sprintf("tile_%03d_%03d.png", row, col),
+			})
+		}
+	}
+
+	// Write to a temporary file and link it into place: the link fails if
+	// another process created the manifest first.
+	data, err := json.MarshalIndent(want, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, manifestName+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Link(tmp.Name(), filepath.Join(dir, manifestName)); err == nil {
+		return want, nil
+	} else if !errors.Is(err, fs.ErrExist) {
+		return nil, err
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if m.Width != width || m.Height != height || m.TileSize != size || !slices.Equal(m.Scene, want.Scene) {
+		return nil, fmt.Errorf("%s was created for a different render (%dx%d in %d px tiles, %s)",
+			filepath.Join(dir, manifestName), m.Width, m.Height, m.TileSize, strings.Join(m.Scene, " "))
+	}
+	return m, nil
+}
+
+func readManifest(dir string) (*tileManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m tileManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, manifestName), err)
+	}
+	return &m, nil
+}
+
+// claimTile claims the first tile that is neither done nor claimed by a live
+// process. It returns a nil release func if there is none. Until release is
+// called, the claim is kept fresh in the background.
+func claimTile(dir string, m *tileManifest) (tileEntry, func(), error) {
+	host, _ := os.Hostname()
+	for _, e := range m.Tiles {
+		if e.Done {
+			continue
+		}
+		claim := filepath.Join(dir, strings.TrimSuffix(e.File, ".png")+".claim")
+		ok, err := createExclusive(claim, fmt.Sprintf("%s %d\n", host, os.Getpid()), staleClaim)
+		if err != nil {
+			return e, nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.File)); err == nil {
+			// Finished since m was read, or finished by a process that died
+			// before it could update the manifest.
+			err := markDone(dir, e)
+			os.Remove(claim)
+			if err != nil {
+				return e, nil, err
+			}
+			continue
+		}
+
+		stop := make(chan struct{})
+		go func() {
+			t := time.NewTicker(claimHeartbeat)
+			defer t.Stop()
+			for {
+				select {
+				case now := <-t.C:
+					os.Chtimes(claim, now, now)
+				case <-stop:
+					return
+				}
+			}
+		}()
+		return e, func() {
+			close(stop)
+			os.Remove(claim)
+		}, nil
+	}
+	return tileEntry{}, nil, nil
+}
+
+// markDone records tile e as done in the manifest.
+func markDone(dir string, e tileEntry) error {
+	lock := filepath.Join(dir, "manifest.lock")
+	for {
+		ok, err := createExclusive(lock, fmt.Sprintf("%d\n", os.Getpid()), staleLock)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer os.Remove(lock)
+
+	m, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+	for i := range m.Tiles {
+		if m.Tiles[i].Col == e.Col && m.Tiles[i].Row == e.Row {
+			m.Tiles[i].Done = true
+		}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, manifestName)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// createExclusive creates path with content unless it already exists and was
+// modified within stale. A stale file is replaced. It reports whether this
+// call created the file.
+//
+// A stale file is moved aside rather than removed, and discarded only if it
+// is still the file that was found stale: two processes may find the same
+// stale file, and by the time the second acts, the first may have replaced
+// it with a fresh one of its own. Only one rename can move the stale file;
+// the loser finds it moved a different file and puts that back.
+func createExclusive(path, content string, stale time.Duration) (bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, err = f.WriteString(content)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return err == nil, err
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return false, err
+		}
+
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // released in the meantime
+		}
+		if err != nil {
+			return false, err
+		}
+		if time.Since(info.ModTime()) < stale {
+			return false, nil
+		}
+
+		aside := fmt.Sprintf("%s.stale.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+		if err := os.Rename(path, aside); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		moved, err := os.Stat(aside)
+		if err != nil {
+			return false, err
+		}
+		if !os.SameFile(info, moved) {
+			// Another process took the stale file over first. If yet
+			// another has created path since, the link fails and both keep
+			// going, which at worst renders a tile twice or loses a
+			// markDone that a later claimTile repairs.
+			os.Link(aside, path)
+			os.Remove(aside)
+			return false, nil
+		}
+		os.Remove(aside)
+	}
+	return false, nil
+}
+
+// assembleTiles streams the finished tiles of the -assemble directory into a
+// tiled TIFF at path, holding one render tile in memory at a time. The TIFF
+// is classic TIFF, so it fails with tiffenc.ErrTooLarge once the compressed
+// image outgrows 4 GiB.
+func assembleTiles(dir, path string, quiet bool) error {
+	m, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range m.Tiles {
+		if !e.Done {
+			return fmt.Errorf("tile %d,%d is not done yet", e.Col, e.Row)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tw, err := tiffenc.NewTiled(f, m.Width, m.Height, tiffenc.DefaultTileSize)
+	if err != nil {
+		return err
+	}
+
+	per := m.TileSize / tiffenc.DefaultTileSize // TIFF tiles per render tile edge
+	across, down := tw.Tiles()
+	for i, e := range m.Tiles {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "\rAssembling %s %d/%d ", path, i+1, len(m.Tiles))
+		}
+		img, err := readTile(filepath.Join(dir, e.File), m.rect(e))
+		if err != nil {
+			return err
+		}
+		for row := e.Row * per; row < min((e.Row+1)*per, down); row++ {
+			for col := e.Col * per; col < min((e.Col+1)*per, across); col++ {
+				if err := tw.WriteTile(col, row, img); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if !quiet {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readTile decodes a tile PNG and places it at rect in frame coordinates.
+func readTile(path string, rect image.Rectangle) (*image.NRGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if src.Bounds().Size() != rect.Size() {
+		return nil, fmt.Errorf("%s is %v, want %v", path, src.Bounds().Size(), rect.Size())
+	}
+	img := image.NewNRGBA(rect)
+	draw.Draw(img, rect, src, src.Bounds().Min, draw.Src)
+	return img, nil
+}
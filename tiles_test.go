@@ -0,0 +1,216 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/echoflaresat/spacecam/render"
+)
+
+// claimPath returns the claim file of tile e in dir.
+func claimPath(dir string, e tileEntry) string {
+	return filepath.Join(dir, strings.TrimSuffix(e.File, ".png")+".claim")
+}
+
+// mustClaim claims a tile, failing the test if there is none.
+func mustClaim(t *testing.T, dir string, m *tileManifest) (tileEntry, func()) {
+	t.Helper()
+	e, release, err := claimTile(dir, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release == nil {
+		t.Fatal("no tile to claim")
+	}
+	return e, release
+}
+
+func TestClaimTile(t *testing.T) {
+	dir := t.TempDir()
+	m, err := openManifest(dir, 600, 300, 256) // 3×2 tiles
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Tiles) != 6 {
+		t.Fatalf("%d tiles, want 6", len(m.Tiles))
+	}
+
+	// Two claimers take different tiles.
+	a, releaseA := mustClaim(t, dir, m)
+	defer releaseA()
+	b, releaseB := mustClaim(t, dir, m)
+	if a.File == b.File {
+		t.Errorf("both claimers got %s", a.File)
+	}
+
+	// A stale claim is taken over; a fresh one is left alone.
+	releaseB()
+	if _, err := os.Stat(claimPath(dir, b)); err == nil {
+		t.Fatal("claim file still exists after release")
+	}
+	old := time.Now().Add(-2 * staleClaim)
+	if err := os.WriteFile(claimPath(dir, b), []byte("dead 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(claimPath(dir, b), old, old)
+	c, releaseC := mustClaim(t, dir, m)
+	defer releaseC()
+	if c.File != b.File {
+		t.Errorf("claimed %s, want the stale %s", c.File, b.File)
+	}
+	d, releaseD := mustClaim(t, dir, m)
+	defer releaseD()
+	if d.File == a.File || d.File == c.File {
+		t.Errorf("claimed %s, which has a fresh claim", d.File)
+	}
+	if stale, _ := filepath.Glob(filepath.Join(dir, "*.stale.*")); len(stale) != 0 {
+		t.Errorf("stale claims left behind: %v", stale)
+	}
+}
+
+func TestCreateExclusiveStaleRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.lock")
+	for round := 0; round < 50; round++ {
+		if err := os.WriteFile(path, []byte("dead\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(path, old, old)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		won := 0
+		start := make(chan struct{})
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				ok, err := createExclusive(path, "live\n", time.Minute)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					mu.Lock()
+					won++
+					mu.Unlock()
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if won != 1 {
+			t.Fatalf("round %d: %d processes took over the stale file, want 1", round, won)
+		}
+		os.Remove(path)
+	}
+}
+
+func TestMarkDone(t *testing.T) {
+	dir := t.TempDir()
+	m, err := openManifest(dir, 512, 256, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := markDone(dir, m.Tiles[1]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tiles[0].Done || !got.Tiles[1].Done {
+		t.Errorf("tiles done = %v, %v; want false, true", got.Tiles[0].Done, got.Tiles[1].Done)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "manifest.lock")); err == nil {
+		t.Error("manifest.lock left behind")
+	}
+}
+
+func TestClaimTileFinishedPNG(t *testing.T) {
+	dir := t.TempDir()
+	m, err := openManifest(dir, 512, 256, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tile 0 was rendered by a process that died before marking it done.
+	if err := writePNG(filepath.Join(dir, m.Tiles[0].File), image.NewNRGBA(m.rect(m.Tiles[0]))); err != nil {
+		t.Fatal(err)
+	}
+
+	e, release := mustClaim(t, dir, m)
+	release()
+	if e.File != m.Tiles[1].File {
+		t.Errorf("claimed %s, want %s", e.File, m.Tiles[1].File)
+	}
+	got, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Tiles[0].Done {
+		t.Error("finished tile not marked done")
+	}
+}
+
+func TestAssembleTiles(t *testing.T) {
+	dir := t.TempDir()
+	m, err := openManifest(dir, 600, 300, 256) // 3×2 tiles, clipped at the edges
+	if err != nil {
+		t.Fatal(err)
+	}
+	pixel := func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8(x), uint8(y), uint8(x/256*16 + y/256), 255}
+	}
+	out := filepath.Join(dir, "out.tif")
+
+	for i, e := range m.Tiles {
+		r := m.rect(e)
+		img := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetNRGBA(x-r.Min.X, y-r.Min.Y, pixel(x, y))
+			}
+		}
+		if err := writePNG(filepath.Join(dir, e.File), img); err != nil {
+			t.Fatal(err)
+		}
+		if i == len(m.Tiles)-1 {
+			if err := assembleTiles(dir, out, true); err == nil {
+				t.Fatal("assembled with a tile not done")
+			}
+		}
+		if err := markDone(dir, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := assembleTiles(dir, out, true); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := render.LoadImage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 600, 300) {
+		t.Fatalf("bounds = %v, want 600x300", img.Bounds())
+	}
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if want := pixel(x, y); uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+				t.Fatalf("pixel (%d, %d) = %d,%d,%d, want %v", x, y, r>>8, g>>8, b>>8, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package vectors
+
+// Mat3 is a 3x3 matrix stored row-major: m[row][col].
+type Mat3 [3][3]float64
+
+// Identity returns the 3x3 identity matrix.
+func Identity() Mat3 {
+	return Mat3{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+	}
+}
+
+// Mat3FromCols builds a matrix whose columns are a, b and c.
+func Mat3FromCols(a, b, c Vec3) Mat3 {
+	return Mat3{
+		{a.X, b.X, c.X},
+		{a.Y, b.Y, c.Y},
+		{a.Z, b.Z, c.Z},
+	}
+}
+
+// Col returns column i (0..2) as a vector.
+func (m Mat3) Col(i int) Vec3 {
+	return Vec3{m[0][i], m[1][i], m[2][i]}
+}
+
+// Row returns row i (0..2) as a vector.
+func (m Mat3) Row(i int) Vec3 {
+	return Vec3{m[i][0], m[i][1], m[i][2]}
+}
+
+// MulVec returns m · v.
+func (m Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		X: m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		Y: m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		Z: m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// Mul returns the matrix product m · o.
+func (m Mat3) Mul(o Mat3) Mat3 {
+	var out Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			out[i][j] = m[i][0]*o[0][j] + m[i][1]*o[1][j] + m[i][2]*o[2][j]
+		}
+	}
+	return out
+}
+
+// Transpose returns mᵀ. For a rotation matrix this is also its inverse.
+func (m Mat3) Transpose() Mat3 {
+	return Mat3{
+		{m[0][0], m[1][0], m[2][0]},
+		{m[0][1], m[1][1], m[2][1]},
+		{m[0][2], m[1][2], m[2][2]},
+	}
+}
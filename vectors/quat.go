@@ -0,0 +1,110 @@
+package vectors
+
+import "math"
+
+// Quat is a quaternion W + Xi + Yj + Zk. Unit quaternions represent rotations.
+type Quat struct {
+	W, X, Y, Z float64
+}
+
+// QuatFromAxisAngle returns the rotation by angle (radians) around axis.
+func QuatFromAxisAngle(axis Vec3, angle float64) Quat {
+	a := axis.Normalize()
+	s := math.Sin(angle / 2)
+	return Quat{W: math.Cos(angle / 2), X: a.X * s, Y: a.Y * s, Z: a.Z * s}
+}
+
+// QuatFromMat3 returns the unit quaternion for the rotation matrix m.
+func QuatFromMat3(m Mat3) Quat {
+	// Shepperd's method: pick the largest diagonal term for stability.
+	tr := m[0][0] + m[1][1] + m[2][2]
+	var q Quat
+	switch {
+	case tr > 0:
+		s := math.Sqrt(tr+1) * 2
+		q = Quat{W: 0.25 * s, X: (m[2][1] - m[1][2]) / s, Y: (m[0][2] - m[2][0]) / s, Z: (m[1][0] - m[0][1]) / s}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := math.Sqrt(1+m[0][0]-m[1][1]-m[2][2]) * 2
+		q = Quat{W: (m[2][1] - m[1][2]) / s, X: 0.25 * s, Y: (m[0][1] + m[1][0]) / s, Z: (m[0][2] + m[2][0]) / s}
+	case m[1][1] > m[2][2]:
+		s := math.Sqrt(1+m[1][1]-m[0][0]-m[2][2]) * 2
+		q = Quat{W: (m[0][2] - m[2][0]) / s, X: (m[0][1] + m[1][0]) / s, Y: 0.25 * s, Z: (m[1][2] + m[2][1]) / s}
+	default:
+		s := math.Sqrt(1+m[2][2]-m[0][0]-m[1][1]) * 2
+		q = Quat{W: (m[1][0] - m[0][1]) / s, X: (m[0][2] + m[2][0]) / s, Y: (m[1][2] + m[2][1]) / s, Z: 0.25 * s}
+	}
+	return q.Normalize()
+}
+
+// Mul returns the Hamilton product q · o (apply o first, then q).
+func (q Quat) Mul(o Quat) Quat {
+	return Quat{
+		W: q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+		X: q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		Y: q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		Z: q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+	}
+}
+
+// Conj returns the conjugate of q, the inverse rotation for unit quaternions.
+func (q Quat) Conj() Quat {
+	return Quat{W: q.W, X: -q.X, Y: -q.Y, Z: -q.Z}
+}
+
+// Norm returns the quaternion length.
+func (q Quat) Norm() float64 {
+	return math.Sqrt(q.W*q.W + q.X*q.X + q.Y*q.Y + q.Z*q.Z)
+}
+
+// Normalize returns q / |q|. If |q| == 0, it returns the identity rotation.
+func (q Quat) Normalize() Quat {
+	n := q.Norm()
+	if n == 0 {
+		return Quat{W: 1}
+	}
+	inv := 1.0 / n
+	return Quat{W: q.W * inv, X: q.X * inv, Y: q.Y * inv, Z: q.Z * inv}
+}
+
+// Rotate applies the rotation q (assumed unit length) to v.
+func (q Quat) Rotate(v Vec3) Vec3 {
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+// Mat3 returns the rotation matrix of q (assumed unit length).
+func (q Quat) Mat3() Mat3 {
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	return Mat3{
+		{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y)},
+		{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x)},
+		{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// Slerp interpolates along the shorter great arc between the unit quaternions
+// q (s = 0) and o (s = 1), at constant angular velocity.
+func (q Quat) Slerp(o Quat, s float64) Quat {
+	dot := q.W*o.W + q.X*o.X + q.Y*o.Y + q.Z*o.Z
+	if dot < 0 {
+		// q and -q are the same rotation; take the short way round.
+		o = Quat{W: -o.W, X: -o.X, Y: -o.Y, Z: -o.Z}
+		dot = -dot
+	}
+
+	a, b := 1-s, s
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		a = math.Sin((1-s)*theta) / sin
+		b = math.Sin(s*theta) / sin
+	}
+	// Nearly parallel: the linear blend is accurate and avoids dividing by ~0.
+	return Quat{
+		W: a*q.W + b*o.W,
+		X: a*q.X + b*o.X,
+		Y: a*q.Y + b*o.Y,
+		Z: a*q.Z + b*o.Z,
+	}.Normalize()
+}